@@ -32,6 +32,62 @@ type DownscalerSpec struct {
 	Config            Config            `json:"config"`
 	Schedule          Schedule          `json:"schedule"`
 	DownscalerOptions DownscalerOptions `json:"downscalerOptions"`
+	// Calendars holds dated exceptions to the rules, such as holidays kept down all day or release
+	// nights kept up.
+	Calendars []Calendar `json:"calendars,omitempty"`
+}
+
+// CalendarAction is what an exception does to the scaling of a rule on its days.
+type CalendarAction string
+
+const (
+	// CalendarActionForceDown keeps the namespaces down: upscales are skipped and they are scaled
+	// down at the start of the day.
+	CalendarActionForceDown CalendarAction = "ForceDown"
+	// CalendarActionForceUp keeps the namespaces up: downscales are skipped and they are scaled up
+	// at the start of the day.
+	CalendarActionForceUp CalendarAction = "ForceUp"
+	// CalendarActionSkip skips every scaling, leaving the namespaces as they are.
+	CalendarActionSkip CalendarAction = "Skip"
+)
+
+// Calendar is a named list of exceptions applied to the rules of the Downscaler. When several
+// exceptions cover the same time the first one wins, in the order of the calendars and, within a
+// calendar, the listed exceptions before the events of the ics file.
+type Calendar struct {
+	Name string `json:"name"`
+	// Rules restricts the calendar to the rules with these names, it applies to every rule by default.
+	Rules      []string            `json:"rules,omitempty"`
+	Exceptions []CalendarException `json:"exceptions,omitempty"`
+	// ICS imports the events of an iCalendar file kept in a ConfigMap.
+	ICS *ICSSource `json:"ics,omitempty"`
+}
+
+// CalendarException covers the days from Date to EndDate, in the time zone of the rule.
+type CalendarException struct {
+	// Date is the first day of the exception, as YYYY-MM-DD.
+	Date string `json:"date"`
+	// EndDate is the last day of the exception, the same as Date by default.
+	EndDate string `json:"endDate,omitempty"`
+	// +kubebuilder:validation:Enum=ForceDown;ForceUp;Skip
+	Action      CalendarAction `json:"action"`
+	Description string         `json:"description,omitempty"`
+}
+
+// ICSSource points at iCalendar content kept in a ConfigMap. Every event of the file applies the
+// same action, the file is read again on each job so edits are picked up without a reconcile.
+type ICSSource struct {
+	ConfigMapRef ConfigMapKeyRef `json:"configMapRef"`
+	// +kubebuilder:validation:Enum=ForceDown;ForceUp;Skip
+	Action CalendarAction `json:"action"`
+}
+
+// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the Downscaler. The namespace may
+// be left out, any other namespace is rejected.
+type ConfigMapKeyRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
 }
 
 type Config struct {
@@ -50,14 +106,89 @@ type TimeRules struct {
 type DownscalerOptions struct {
 	TimeRules       *TimeRules           `json:"timeRules"`
 	ResourceScaling []types.ResourceType `json:"resourceScaling"`
+
+	// ScalableResources declares custom resources exposing the /scale subresource. Each name can
+	// be used in resourceScaling and overrideScaling like a built-in resource type.
+	ScalableResources []ScalableResource `json:"scalableResources,omitempty"`
+}
+
+// ScalableResource identifies a custom resource, such as an Argo Rollout, whose replicas are
+// read and written through the /scale subresource.
+type ScalableResource struct {
+	Name    types.ResourceType `json:"name"`
+	Group   string             `json:"group"`
+	Version string             `json:"version"`
+	Kind    string             `json:"kind"`
 }
 
 type Rules struct {
-	Name            string               `json:"name"`
-	Namespaces      []Namespace          `json:"namespaces"`
-	UpscaleTime     string               `json:"upscaleTime"`
-	DownscaleTime   string               `json:"downscaleTime"`
+	Name       string      `json:"name"`
+	Namespaces []Namespace `json:"namespaces,omitempty"`
+	// NamespaceSelector selects namespaces by label when the job runs, so namespaces created
+	// after the object was applied are scaled as well. Namespaces listed literally by any rule
+	// are left to that rule.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// WorkloadSelector restricts the rule to the objects carrying matching labels. Objects
+	// annotated with kubetime-scaler/exclude: "true" are skipped regardless of the selector.
+	WorkloadSelector *metav1.LabelSelector `json:"workloadSelector,omitempty"`
+
+	UpscaleTime   string `json:"upscaleTime,omitempty"`
+	DownscaleTime string `json:"downscaleTime,omitempty"`
+	// DownscaleCron and UpscaleCron replace downscaleTime and upscaleTime with a cron expression,
+	// with or without the seconds field, or a descriptor such as @hourly. The recurrence is not
+	// applied to them.
+	DownscaleCron string `json:"downscaleCron,omitempty"`
+	UpscaleCron   string `json:"upscaleCron,omitempty"`
+	// Windows adds downscale/upscale pairs to the one of downscaleTime and upscaleTime, so a rule
+	// can scale its namespaces down more than once a day. The windows must not overlap.
+	Windows []Window `json:"windows,omitempty"`
+	// TimeZone and Recurrence replace the ones of the schedule for this rule, so rules of teams in
+	// different time zones, or sleeping on different days, can share the same object.
+	TimeZone   string `json:"timeZone,omitempty"`
+	Recurrence string `json:"recurrence,omitempty"`
+	// CatchUp scales the namespaces to the state expected at the current time when the controller
+	// starts and after each reconcile, instead of waiting for the next downscale or upscale. True by
+	// default.
+	CatchUp *bool `json:"catchUp,omitempty"`
+
 	OverrideScaling []types.ResourceType `json:"overrideScaling,omitempty"`
+
+	// DownscaleReplicas is the replica count kept during the downscale, 0 by default.
+	// +kubebuilder:validation:Minimum=0
+	DownscaleReplicas *int32 `json:"downscaleReplicas,omitempty"`
+	// UpscaleReplicas is the replica count used on upscale when no replicas were recorded,
+	// in memory mode or for objects the store does not know about, 1 by default.
+	// +kubebuilder:validation:Minimum=0
+	UpscaleReplicas *int32 `json:"upscaleReplicas,omitempty"`
+}
+
+// Window is one downscale/upscale pair of a rule.
+type Window struct {
+	DownscaleTime string `json:"downscaleTime,omitempty"`
+	UpscaleTime   string `json:"upscaleTime,omitempty"`
+	DownscaleCron string `json:"downscaleCron,omitempty"`
+	UpscaleCron   string `json:"upscaleCron,omitempty"`
+	// Recurrence replaces the one of the rule, or of the schedule, for this window.
+	Recurrence string `json:"recurrence,omitempty"`
+}
+
+// CatchUpEnabled reports whether the rule is caught up on start and reconcile.
+func (r Rules) CatchUpEnabled() bool {
+	return r.CatchUp == nil || *r.CatchUp
+}
+
+// ScheduleWindows returns every window of the rule, the one of downscaleTime and upscaleTime first.
+func (r Rules) ScheduleWindows() []Window {
+	var windows []Window
+	if r.DownscaleTime != "" || r.UpscaleTime != "" || r.DownscaleCron != "" || r.UpscaleCron != "" || len(r.Windows) == 0 {
+		windows = append(windows, Window{
+			DownscaleTime: r.DownscaleTime,
+			UpscaleTime:   r.UpscaleTime,
+			DownscaleCron: r.DownscaleCron,
+			UpscaleCron:   r.UpscaleCron,
+		})
+	}
+	return append(windows, r.Windows...)
 }
 
 type Namespace string
@@ -75,10 +206,53 @@ func (ns Namespace) Found(collection []Namespace) bool {
 	return false
 }
 
+// ScalingPhase is the last known scaling state of a namespace governed by a rule.
+type ScalingPhase string
+
+const (
+	PhaseUp            ScalingPhase = "Up"
+	PhaseDown          ScalingPhase = "Down"
+	PhaseTransitioning ScalingPhase = "Transitioning"
+	PhaseFailed        ScalingPhase = "Failed"
+)
+
+const (
+	// ConditionReady reports whether the cron scheduler of the object is running.
+	ConditionReady = "Ready"
+	// ConditionScheduleValid reports whether every rule could be turned into a cron entry.
+	ConditionScheduleValid = "ScheduleValid"
+	// ConditionDatabaseAvailable reports whether the database of the store answered the last job.
+	ConditionDatabaseAvailable = "DatabaseAvailable"
+	// ConditionCalendarsValid reports whether every calendar, with the events of its ics file, was read.
+	ConditionCalendarsValid = "CalendarsValid"
+)
+
 // DownscalerStatus defines the observed state of Downscaler
 type DownscalerStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	Rules              []RuleStatus `json:"rules,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type RuleStatus struct {
+	Name       string            `json:"name"`
+	Namespaces []NamespaceStatus `json:"namespaces,omitempty"`
+}
+
+type NamespaceStatus struct {
+	Name              string       `json:"name"`
+	Phase             ScalingPhase `json:"phase,omitempty"`
+	LastDownscaleTime *metav1.Time `json:"lastDownscaleTime,omitempty"`
+	LastUpscaleTime   *metav1.Time `json:"lastUpscaleTime,omitempty"`
+	NextDownscaleTime *metav1.Time `json:"nextDownscaleTime,omitempty"`
+	NextUpscaleTime   *metav1.Time `json:"nextUpscaleTime,omitempty"`
+	Message           string       `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,479 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var downscalerlog = logf.Log.WithName("downscaler-resource")
+
+// SetupDownscalerWebhookWithManager registers the validating webhook for Downscaler in the manager.
+func SetupDownscalerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&Downscaler{}).
+		WithValidator(&DownscalerCustomValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-downscaler-go-v1alpha1-downscaler,mutating=false,failurePolicy=fail,sideEffects=None,groups=downscaler.go,resources=downscalers,verbs=create;update,versions=v1alpha1,name=vdownscaler.kb.io,admissionReviewVersions=v1
+
+// DownscalerCustomValidator rejects Downscaler objects that the scheduler would not be able to run.
+// +kubebuilder:object:generate=false
+type DownscalerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &DownscalerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *DownscalerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	downscaler, ok := obj.(*Downscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a Downscaler object but got %T", obj)
+	}
+	downscalerlog.Info("validate create", "name", downscaler.Name)
+
+	return nil, downscaler.validate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *DownscalerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	downscaler, ok := newObj.(*Downscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a Downscaler object but got %T", newObj)
+	}
+	downscalerlog.Info("validate update", "name", downscaler.Name)
+
+	// removing the finalizer of an object being deleted must go through even when the object no
+	// longer passes validation, otherwise it stays terminating forever.
+	if downscaler.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	return nil, downscaler.validate()
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *DownscalerCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *Downscaler) validate() error {
+	allErrs := ValidateDownscaler(r)
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Downscaler").GroupKind(), r.Name, allErrs)
+}
+
+// ValidateDownscaler runs every admission check against the object. It is shared by the
+// webhook and the reconciler, so objects created while the webhook is disabled are still
+// rejected before being scheduled.
+func ValidateDownscaler(r *Downscaler) field.ErrorList {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	allErrs = append(allErrs, validateSchedule(&r.Spec.Schedule, specPath.Child("schedule"))...)
+
+	// a broken recurrence is reported once by validateSchedule instead of once per rule time.
+	recurrence := r.Spec.Schedule.Recurrence
+	if validateRecurrence(recurrence) != nil {
+		recurrence = "*"
+	}
+	allErrs = append(allErrs, validateDownscalerOptions(&r.Spec.DownscalerOptions, recurrence, specPath.Child("downscalerOptions"))...)
+	allErrs = append(allErrs, validateCalendars(r.Spec.Calendars, r.Spec.DownscalerOptions.TimeRules, r.Namespace, specPath.Child("calendars"))...)
+
+	return allErrs
+}
+
+var calendarActions = []CalendarAction{CalendarActionForceDown, CalendarActionForceUp, CalendarActionSkip}
+
+// validateCalendars checks the dates and actions of the exceptions and that the calendars name
+// existing rules. The content of the ics files is only read when the jobs run, from a ConfigMap of
+// the namespace of the Downscaler so a Downscaler can't read the ConfigMaps of other namespaces.
+func validateCalendars(calendars []Calendar, timeRules *TimeRules, namespace string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	var ruleNames []string
+	if timeRules != nil {
+		for _, rule := range timeRules.Rules {
+			ruleNames = append(ruleNames, rule.Name)
+		}
+	}
+
+	var names []string
+	for index, calendar := range calendars {
+		calendarPath := fldPath.Index(index)
+
+		switch {
+		case calendar.Name == "":
+			allErrs = append(allErrs, field.Required(calendarPath.Child("name"), "name is required"))
+		case slices.Contains(names, calendar.Name):
+			allErrs = append(allErrs, field.Duplicate(calendarPath.Child("name"), calendar.Name))
+		default:
+			names = append(names, calendar.Name)
+		}
+
+		for ruleIndex, rule := range calendar.Rules {
+			if !slices.Contains(ruleNames, rule) {
+				allErrs = append(allErrs, field.NotFound(calendarPath.Child("rules").Index(ruleIndex), rule))
+			}
+		}
+
+		for exceptionIndex, exception := range calendar.Exceptions {
+			allErrs = append(allErrs, validateCalendarException(exception, calendarPath.Child("exceptions").Index(exceptionIndex))...)
+		}
+
+		if calendar.ICS != nil {
+			icsPath := calendarPath.Child("ics")
+			if calendar.ICS.ConfigMapRef.Name == "" {
+				allErrs = append(allErrs, field.Required(icsPath.Child("configMapRef", "name"), "name is required"))
+			}
+			if calendar.ICS.ConfigMapRef.Key == "" {
+				allErrs = append(allErrs, field.Required(icsPath.Child("configMapRef", "key"), "key is required"))
+			}
+			if configMapNamespace := calendar.ICS.ConfigMapRef.Namespace; configMapNamespace != "" && configMapNamespace != namespace {
+				allErrs = append(allErrs, field.Forbidden(icsPath.Child("configMapRef", "namespace"), "the ConfigMap must be in the namespace of the Downscaler"))
+			}
+			if !slices.Contains(calendarActions, calendar.ICS.Action) {
+				allErrs = append(allErrs, field.NotSupported(icsPath.Child("action"), calendar.ICS.Action, calendarActions))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateCalendarException(exception CalendarException, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	date, err := time.Parse(time.DateOnly, exception.Date)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("date"), exception.Date, "expected YYYY-MM-DD"))
+	}
+
+	if exception.EndDate != "" {
+		endDate, err := time.Parse(time.DateOnly, exception.EndDate)
+		switch {
+		case err != nil:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("endDate"), exception.EndDate, "expected YYYY-MM-DD"))
+		case !date.IsZero() && endDate.Before(date):
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("endDate"), exception.EndDate, "must not be before date"))
+		}
+	}
+
+	if !slices.Contains(calendarActions, exception.Action) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("action"), exception.Action, calendarActions))
+	}
+
+	return allErrs
+}
+
+func validateSchedule(schedule *Schedule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if schedule.TimeZone == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("timeZone"), "time zone is required"))
+	} else if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("timeZone"), schedule.TimeZone, "Invalid time zone"))
+	}
+
+	if err := validateRecurrence(schedule.Recurrence); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("recurrence"), schedule.Recurrence, err.Error()))
+	}
+
+	return allErrs
+}
+
+func validateDownscalerOptions(options *DownscalerOptions, recurrence string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	supported, scalableErrs := validateScalableResources(options.ScalableResources, fldPath.Child("scalableResources"))
+	allErrs = append(allErrs, scalableErrs...)
+	allErrs = append(allErrs, validateResourceTypes(options.ResourceScaling, supported, fldPath.Child("resourceScaling"))...)
+
+	if options.TimeRules == nil {
+		return append(allErrs, field.Required(fldPath.Child("timeRules"), "TimeRules is required"))
+	}
+
+	rulesPath := fldPath.Child("timeRules").Child("rules")
+	if len(options.TimeRules.Rules) == 0 {
+		return append(allErrs, field.Required(rulesPath, "At least one rule is required"))
+	}
+
+	seenNamespaces := make(map[Namespace]int)
+	for index, rule := range options.TimeRules.Rules {
+		rulePath := rulesPath.Index(index)
+
+		if len(rule.Namespaces) == 0 && rule.NamespaceSelector == nil {
+			allErrs = append(allErrs, field.Required(rulePath.Child("namespaces"), "Namespaces cannot be empty without a namespaceSelector"))
+		}
+
+		if rule.NamespaceSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(rule.NamespaceSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("namespaceSelector"), rule.NamespaceSelector, err.Error()))
+			}
+		}
+
+		if rule.WorkloadSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(rule.WorkloadSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("workloadSelector"), rule.WorkloadSelector, err.Error()))
+			}
+		}
+
+		for nsIndex, namespace := range rule.Namespaces {
+			if previous, found := seenNamespaces[namespace]; found {
+				allErrs = append(allErrs, field.Duplicate(rulePath.Child("namespaces").Index(nsIndex),
+					fmt.Sprintf("%s (already declared by rule %d)", namespace, previous)))
+				continue
+			}
+			seenNamespaces[namespace] = index
+		}
+
+		if rule.TimeZone != "" {
+			if _, err := time.LoadLocation(rule.TimeZone); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("timeZone"), rule.TimeZone, "Invalid time zone"))
+			}
+		}
+
+		// the times are checked against the recurrence of the rule only when it is valid itself.
+		ruleRecurrence := recurrence
+		if rule.Recurrence != "" {
+			if err := validateRecurrence(rule.Recurrence); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("recurrence"), rule.Recurrence, err.Error()))
+			} else {
+				ruleRecurrence = rule.Recurrence
+			}
+		}
+
+		allErrs = append(allErrs, validateWindows(rule, ruleRecurrence, rulePath)...)
+
+		allErrs = append(allErrs, validateResourceTypes(rule.OverrideScaling, supported, rulePath.Child("overrideScaling"))...)
+
+		if rule.DownscaleReplicas != nil && *rule.DownscaleReplicas < 0 {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("downscaleReplicas"), *rule.DownscaleReplicas, "must be greater than or equal to 0"))
+		}
+		if rule.UpscaleReplicas != nil && *rule.UpscaleReplicas < 0 {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("upscaleReplicas"), *rule.UpscaleReplicas, "must be greater than or equal to 0"))
+		}
+	}
+
+	return allErrs
+}
+
+// windowInterval is one activation of a window, from its downscale to the following upscale.
+type windowInterval struct {
+	downscale, upscale time.Time
+}
+
+// validateWindows checks the times of every window of the rule and that no two windows keep the
+// namespaces down at the same time. The windows with invalid times are left out of the overlap check.
+func validateWindows(rule Rules, recurrence string, rulePath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	windows := rule.ScheduleWindows()
+	intervals := make([][]windowInterval, len(windows))
+	paths := make([]*field.Path, len(windows))
+
+	for index, window := range windows {
+		// the downscaleTime and upscaleTime of the rule are reported on the rule itself.
+		windowPath := rulePath
+		if offset := len(windows) - len(rule.Windows); index >= offset {
+			windowPath = rulePath.Child("windows").Index(index - offset)
+		}
+		paths[index] = windowPath
+
+		windowRecurrence := recurrence
+		if window.Recurrence != "" {
+			if err := validateRecurrence(window.Recurrence); err != nil {
+				allErrs = append(allErrs, field.Invalid(windowPath.Child("recurrence"), window.Recurrence, err.Error()))
+				continue
+			}
+			windowRecurrence = window.Recurrence
+		}
+
+		upscaleExpression, upscaleErrs := validateWindowTime(window.UpscaleTime, window.UpscaleCron, windowRecurrence,
+			windowPath.Child("upscaleTime"), windowPath.Child("upscaleCron"))
+		allErrs = append(allErrs, upscaleErrs...)
+
+		downscaleExpression, downscaleErrs := validateWindowTime(window.DownscaleTime, window.DownscaleCron, windowRecurrence,
+			windowPath.Child("downscaleTime"), windowPath.Child("downscaleCron"))
+		allErrs = append(allErrs, downscaleErrs...)
+
+		if len(upscaleErrs) == 0 && len(downscaleErrs) == 0 {
+			intervals[index] = windowIntervals(downscaleExpression, upscaleExpression)
+		}
+	}
+
+	for index := range windows {
+		for previous := 0; previous < index; previous++ {
+			if overlaps(intervals[previous], intervals[index]) {
+				allErrs = append(allErrs, field.Invalid(paths[index], windowDescription(windows[index]),
+					fmt.Sprintf("window overlaps with %s", paths[previous])))
+				break
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// validateWindowTime checks one side of a window, set either as a time with the recurrence or as a
+// raw cron expression, and returns the expression it is scheduled with.
+func validateWindowTime(timeStr, cronExpression, recurrence string, timePath, cronPath *field.Path) (string, field.ErrorList) {
+	if timeStr != "" && cronExpression != "" {
+		return "", field.ErrorList{field.Invalid(cronPath, cronExpression, fmt.Sprintf("must not be set together with %s", timePath.String()))}
+	}
+
+	expression, err := utils.ScheduleExpression(cronExpression, recurrence, timeStr)
+	if err != nil {
+		if cronExpression != "" {
+			return "", field.ErrorList{field.Invalid(cronPath, cronExpression, err.Error())}
+		}
+		return "", field.ErrorList{field.Invalid(timePath, timeStr, err.Error())}
+	}
+
+	return expression, nil
+}
+
+func windowDescription(window Window) string {
+	downscale, upscale := window.DownscaleTime, window.UpscaleTime
+	if window.DownscaleCron != "" {
+		downscale = window.DownscaleCron
+	}
+	if window.UpscaleCron != "" {
+		upscale = window.UpscaleCron
+	}
+	return downscale + "-" + upscale
+}
+
+// maxWindowActivations bounds the activations compared for a window, a raw cron expression such as
+// "@every 1s" would otherwise produce one per second of the two weeks.
+const maxWindowActivations = 500
+
+// windowIntervals returns the activations of the window starting during two weeks, so a window
+// running over the end of the first week is compared with the start of the next one. The times
+// are compared on the clock of the rule, the time zone does not matter.
+func windowIntervals(downscaleExpression, upscaleExpression string) []windowInterval {
+	downscale, err := utils.CronParser.Parse(downscaleExpression)
+	if err != nil {
+		return nil
+	}
+	upscale, err := utils.CronParser.Parse(upscaleExpression)
+	if err != nil {
+		return nil
+	}
+
+	// 2024-01-01 is a monday.
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 14)
+
+	var intervals []windowInterval
+	for next := downscale.Next(start.Add(-time.Second)); next.Before(end) && len(intervals) < maxWindowActivations; next = downscale.Next(next) {
+		intervals = append(intervals, windowInterval{downscale: next, upscale: upscale.Next(next)})
+	}
+
+	return intervals
+}
+
+func overlaps(a, b []windowInterval) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.downscale.Before(y.upscale) && y.downscale.Before(x.upscale) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateScalableResources returns the built-in resource types followed by every declared name,
+// which is the list resourceScaling and overrideScaling are checked against.
+func validateScalableResources(resources []ScalableResource, fldPath *field.Path) ([]types.ResourceType, field.ErrorList) {
+	var allErrs field.ErrorList
+	supported := append([]types.ResourceType{}, types.SupportedResourceTypes...)
+
+	for index, resource := range resources {
+		resourcePath := fldPath.Index(index)
+
+		switch {
+		case resource.Name == "":
+			allErrs = append(allErrs, field.Required(resourcePath.Child("name"), "name is required"))
+		case slices.Contains(supported, resource.Name):
+			allErrs = append(allErrs, field.Duplicate(resourcePath.Child("name"), resource.Name))
+		default:
+			supported = append(supported, resource.Name)
+		}
+
+		if resource.Version == "" {
+			allErrs = append(allErrs, field.Required(resourcePath.Child("version"), "version is required"))
+		}
+		if resource.Kind == "" {
+			allErrs = append(allErrs, field.Required(resourcePath.Child("kind"), "kind is required"))
+		}
+	}
+
+	return supported, allErrs
+}
+
+func validateResourceTypes(resources []types.ResourceType, supported []types.ResourceType, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for index, resource := range resources {
+		if !slices.Contains(supported, resource) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(index), resource, supported))
+		}
+	}
+
+	return allErrs
+}
+
+func validateRecurrence(recurrence string) error {
+	if recurrence == "" {
+		return fmt.Errorf("recurrence is required")
+	}
+
+	return validateCronExpression(recurrence, "00:00")
+}
+
+func validateCronExpression(recurrence, timeStr string) error {
+	expression, err := utils.BuildCronExpression(recurrence, timeStr)
+	if err != nil {
+		return err
+	}
+
+	if _, err := utils.CronParser.Parse(expression); err != nil {
+		return fmt.Errorf("invalid cron expression %q: %v", expression, err)
+	}
+
+	return nil
+}
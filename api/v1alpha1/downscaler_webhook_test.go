@@ -0,0 +1,296 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func validDownscaler() *Downscaler {
+	return &Downscaler{
+		Spec: DownscalerSpec{
+			Schedule: Schedule{TimeZone: "America/Sao_Paulo", Recurrence: "MON-FRI"},
+			DownscalerOptions: DownscalerOptions{
+				ResourceScaling: []types.ResourceType{types.DeploymentObjectResource, types.StatefulSetObjectResource},
+				TimeRules: &TimeRules{
+					Rules: []Rules{
+						{Name: "rule-a", Namespaces: []Namespace{"app1", "app2"}, DownscaleTime: "20:00", UpscaleTime: "08:00"},
+						{Name: "rule-b", Namespaces: []Namespace{"app3"}, DownscaleTime: "22:00:30", UpscaleTime: "07:30"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateDownscaler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mutate        func(d *Downscaler)
+		expectedPaths []string
+	}{
+		{
+			name:   "valid object",
+			mutate: func(d *Downscaler) {},
+		},
+		{
+			name:          "unknown time zone",
+			mutate:        func(d *Downscaler) { d.Spec.Schedule.TimeZone = "America/Nowhere" },
+			expectedPaths: []string{"spec.schedule.timeZone"},
+		},
+		{
+			name:          "unparseable recurrence is reported once",
+			mutate:        func(d *Downscaler) { d.Spec.Schedule.Recurrence = "MON-FUNDAY" },
+			expectedPaths: []string{"spec.schedule.recurrence"},
+		},
+		{
+			name:          "invalid upscale time",
+			mutate:        func(d *Downscaler) { d.Spec.DownscalerOptions.TimeRules.Rules[1].UpscaleTime = "25:00" },
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[1].upscaleTime"},
+		},
+		{
+			name:          "missing downscale time",
+			mutate:        func(d *Downscaler) { d.Spec.DownscalerOptions.TimeRules.Rules[0].DownscaleTime = "" },
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[0].downscaleTime"},
+		},
+		{
+			name: "unknown resource type",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.ResourceScaling = append(d.Spec.DownscalerOptions.ResourceScaling, "replicasets")
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].OverrideScaling = []types.ResourceType{"daemonsets"}
+			},
+			expectedPaths: []string{
+				"spec.downscalerOptions.resourceScaling[2]",
+				"spec.downscalerOptions.timeRules.rules[0].overrideScaling[0]",
+			},
+		},
+		{
+			name: "declared scalable resource",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.ScalableResources = []ScalableResource{
+					{Name: "rollouts", Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"},
+				}
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].OverrideScaling = []types.ResourceType{"rollouts"}
+			},
+		},
+		{
+			name: "invalid scalable resources",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.ScalableResources = []ScalableResource{
+					{Name: "deployments", Group: "apps", Version: "v1", Kind: "Deployment"},
+					{Name: "rollouts", Group: "argoproj.io"},
+				}
+			},
+			expectedPaths: []string{
+				"spec.downscalerOptions.scalableResources[0].name",
+				"spec.downscalerOptions.scalableResources[1].version",
+				"spec.downscalerOptions.scalableResources[1].kind",
+			},
+		},
+		{
+			name: "namespace declared by two rules",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].Namespaces = append(d.Spec.DownscalerOptions.TimeRules.Rules[1].Namespaces, "app1")
+			},
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[1].namespaces[1]"},
+		},
+		{
+			name: "namespace selector without namespaces",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].Namespaces = nil
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "preview"}}
+			},
+		},
+		{
+			name: "invalid namespace selector",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].NamespaceSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Like", Values: []string{"preview"}}},
+				}
+			},
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[1].namespaceSelector"},
+		},
+		{
+			name: "invalid workload selector",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].WorkloadSelector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"tier": "not a valid label value"},
+				}
+			},
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[0].workloadSelector"},
+		},
+		{
+			name: "negative replicas",
+			mutate: func(d *Downscaler) {
+				downscaleReplicas, upscaleReplicas := int32(-1), int32(2)
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].DownscaleReplicas = &downscaleReplicas
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].UpscaleReplicas = &upscaleReplicas
+			},
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[0].downscaleReplicas"},
+		},
+		{
+			name: "rule time zone and recurrence",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].TimeZone = "Europe/Lisbon"
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].Recurrence = "SAT,SUN"
+			},
+		},
+		{
+			name: "invalid rule time zone and recurrence",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].TimeZone = "Europe/Nowhere"
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].Recurrence = "SAT-FUNDAY"
+			},
+			expectedPaths: []string{
+				"spec.downscalerOptions.timeRules.rules[0].timeZone",
+				"spec.downscalerOptions.timeRules.rules[1].recurrence",
+			},
+		},
+		{
+			name: "lunch and overnight windows",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].Windows = []Window{{DownscaleTime: "12:00", UpscaleTime: "13:00"}}
+				d.Spec.DownscalerOptions.TimeRules.Rules[1] = Rules{Name: "rule-b", Namespaces: []Namespace{"app3"}, Windows: []Window{
+					{DownscaleTime: "12:00", UpscaleTime: "13:00"},
+					{DownscaleTime: "20:00", UpscaleTime: "08:00"},
+					{DownscaleTime: "09:00", UpscaleTime: "10:00", Recurrence: "SAT"},
+				}}
+				d.Spec.Schedule.Recurrence = "@daily"
+			},
+		},
+		{
+			name: "overlapping windows",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].Windows = []Window{
+					{DownscaleTime: "07:00", UpscaleTime: "09:00"},
+					{DownscaleTime: "25:00", UpscaleTime: "13:00"},
+				}
+				// the friday night window keeps the namespace down until monday morning.
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].Windows = []Window{{DownscaleTime: "10:00", UpscaleTime: "11:00", Recurrence: "SUN"}}
+			},
+			expectedPaths: []string{
+				"spec.downscalerOptions.timeRules.rules[0].windows[1].downscaleTime",
+				"spec.downscalerOptions.timeRules.rules[0].windows[0]",
+				"spec.downscalerOptions.timeRules.rules[1].windows[0]",
+			},
+		},
+		{
+			name: "raw cron expressions",
+			mutate: func(d *Downscaler) {
+				rule := &d.Spec.DownscalerOptions.TimeRules.Rules[0]
+				rule.DownscaleTime, rule.DownscaleCron = "", "0 20 * * MON-FRI"
+				rule.UpscaleTime, rule.UpscaleCron = "", "0 0 8 * * MON-FRI"
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].Windows = []Window{{DownscaleCron: "@every 1h", UpscaleTime: "08:00"}}
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].DownscaleTime = ""
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].UpscaleTime = ""
+			},
+		},
+		{
+			name: "invalid raw cron expressions",
+			mutate: func(d *Downscaler) {
+				rule := &d.Spec.DownscalerOptions.TimeRules.Rules[0]
+				rule.DownscaleTime, rule.DownscaleCron = "", "0 61 * * *"
+				rule.UpscaleTime, rule.UpscaleCron = "", "CRON_TZ=UTC 0 8 * * *"
+				d.Spec.DownscalerOptions.TimeRules.Rules[1].DownscaleCron = "0 22 * * *"
+			},
+			expectedPaths: []string{
+				"spec.downscalerOptions.timeRules.rules[0].upscaleCron",
+				"spec.downscalerOptions.timeRules.rules[0].downscaleCron",
+				"spec.downscalerOptions.timeRules.rules[1].downscaleCron",
+			},
+		},
+		{
+			name: "raw cron window overlapping",
+			mutate: func(d *Downscaler) {
+				d.Spec.DownscalerOptions.TimeRules.Rules[0].Windows = []Window{{DownscaleCron: "0 21 * * MON-FRI", UpscaleCron: "0 22 * * MON-FRI"}}
+			},
+			expectedPaths: []string{"spec.downscalerOptions.timeRules.rules[0].windows[0]"},
+		},
+		{
+			name: "calendars",
+			mutate: func(d *Downscaler) {
+				d.Namespace = "team-a"
+				d.Spec.Calendars = []Calendar{
+					{Name: "holidays", Exceptions: []CalendarException{
+						{Date: "2024-12-25", Action: CalendarActionForceDown},
+						{Date: "2024-12-27", EndDate: "2025-01-03", Action: CalendarActionForceDown},
+					}},
+					{Name: "release", Rules: []string{"rule-b"}, ICS: &ICSSource{
+						ConfigMapRef: ConfigMapKeyRef{Name: "release-calendar", Key: "calendar.ics"},
+						Action:       CalendarActionForceUp,
+					}},
+					{Name: "payroll", ICS: &ICSSource{
+						ConfigMapRef: ConfigMapKeyRef{Name: "payroll-calendar", Namespace: "team-a", Key: "calendar.ics"},
+						Action:       CalendarActionSkip,
+					}},
+				}
+			},
+		},
+		{
+			name: "invalid calendars",
+			mutate: func(d *Downscaler) {
+				d.Spec.Calendars = []Calendar{
+					{Name: "holidays", Rules: []string{"rule-c"}, Exceptions: []CalendarException{
+						{Date: "25/12/2024", Action: CalendarActionForceDown},
+						{Date: "2025-01-03", EndDate: "2024-12-27", Action: "Sleep"},
+					}},
+					{Name: "holidays", ICS: &ICSSource{}},
+					{Name: "payroll", ICS: &ICSSource{
+						ConfigMapRef: ConfigMapKeyRef{Name: "payroll-calendar", Namespace: "kube-system", Key: "calendar.ics"},
+						Action:       CalendarActionSkip,
+					}},
+				}
+			},
+			expectedPaths: []string{
+				"spec.calendars[0].rules[0]",
+				"spec.calendars[0].exceptions[0].date",
+				"spec.calendars[0].exceptions[1].endDate",
+				"spec.calendars[0].exceptions[1].action",
+				"spec.calendars[1].name",
+				"spec.calendars[1].ics.configMapRef.name",
+				"spec.calendars[1].ics.configMapRef.key",
+				"spec.calendars[1].ics.action",
+				"spec.calendars[2].ics.configMapRef.namespace",
+			},
+		},
+		{
+			name:          "missing rules",
+			mutate:        func(d *Downscaler) { d.Spec.DownscalerOptions.TimeRules = nil },
+			expectedPaths: []string{"spec.downscalerOptions.timeRules"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			downscaler := validDownscaler()
+			tc.mutate(downscaler)
+
+			var paths []string
+			for _, err := range ValidateDownscaler(downscaler) {
+				paths = append(paths, err.Field)
+			}
+			assert.Equal(t, tc.expectedPaths, paths)
+		})
+	}
+}
+
+func TestDownscalerCustomValidator(t *testing.T) {
+	validator := &DownscalerCustomValidator{}
+
+	_, err := validator.ValidateCreate(context.Background(), validDownscaler())
+	assert.NoError(t, err)
+
+	invalid := validDownscaler()
+	invalid.Spec.DownscalerOptions.TimeRules.Rules[0].Namespaces = nil
+	_, err = validator.ValidateUpdate(context.Background(), validDownscaler(), invalid)
+	assert.ErrorContains(t, err, field.NewPath("spec", "downscalerOptions", "timeRules", "rules").Index(0).Child("namespaces").String())
+
+	now := metav1.Now()
+	invalid.DeletionTimestamp = &now
+	_, err = validator.ValidateUpdate(context.Background(), validDownscaler(), invalid)
+	assert.NoError(t, err, "an object being deleted must be able to drop its finalizer")
+}
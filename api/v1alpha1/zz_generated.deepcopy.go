@@ -22,9 +22,55 @@ package v1alpha1
 
 import (
 	"github.com/adalbertjnr/kubetime-scaler/internal/types"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Calendar) DeepCopyInto(out *Calendar) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exceptions != nil {
+		in, out := &in.Exceptions, &out.Exceptions
+		*out = make([]CalendarException, len(*in))
+		copy(*out, *in)
+	}
+	if in.ICS != nil {
+		in, out := &in.ICS, &out.ICS
+		*out = new(ICSSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Calendar.
+func (in *Calendar) DeepCopy() *Calendar {
+	if in == nil {
+		return nil
+	}
+	out := new(Calendar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarException) DeepCopyInto(out *CalendarException) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarException.
+func (in *CalendarException) DeepCopy() *CalendarException {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarException)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
@@ -40,13 +86,28 @@ func (in *Config) DeepCopy() *Config {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyRef) DeepCopyInto(out *ConfigMapKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyRef.
+func (in *ConfigMapKeyRef) DeepCopy() *ConfigMapKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Downscaler) DeepCopyInto(out *Downscaler) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Downscaler.
@@ -112,6 +173,11 @@ func (in *DownscalerOptions) DeepCopyInto(out *DownscalerOptions) {
 		*out = make([]types.ResourceType, len(*in))
 		copy(*out, *in)
 	}
+	if in.ScalableResources != nil {
+		in, out := &in.ScalableResources, &out.ScalableResources
+		*out = make([]ScalableResource, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownscalerOptions.
@@ -130,6 +196,13 @@ func (in *DownscalerSpec) DeepCopyInto(out *DownscalerSpec) {
 	out.Config = in.Config
 	out.Schedule = in.Schedule
 	in.DownscalerOptions.DeepCopyInto(&out.DownscalerOptions)
+	if in.Calendars != nil {
+		in, out := &in.Calendars, &out.Calendars
+		*out = make([]Calendar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownscalerSpec.
@@ -145,6 +218,20 @@ func (in *DownscalerSpec) DeepCopy() *DownscalerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DownscalerStatus) DeepCopyInto(out *DownscalerStatus) {
 	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]RuleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownscalerStatus.
@@ -157,6 +244,75 @@ func (in *DownscalerStatus) DeepCopy() *DownscalerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICSSource) DeepCopyInto(out *ICSSource) {
+	*out = *in
+	out.ConfigMapRef = in.ConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICSSource.
+func (in *ICSSource) DeepCopy() *ICSSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ICSSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceStatus) DeepCopyInto(out *NamespaceStatus) {
+	*out = *in
+	if in.LastDownscaleTime != nil {
+		in, out := &in.LastDownscaleTime, &out.LastDownscaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastUpscaleTime != nil {
+		in, out := &in.LastUpscaleTime, &out.LastUpscaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextDownscaleTime != nil {
+		in, out := &in.NextDownscaleTime, &out.NextDownscaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextUpscaleTime != nil {
+		in, out := &in.NextUpscaleTime, &out.NextUpscaleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceStatus.
+func (in *NamespaceStatus) DeepCopy() *NamespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleStatus) DeepCopyInto(out *RuleStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleStatus.
+func (in *RuleStatus) DeepCopy() *RuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rules) DeepCopyInto(out *Rules) {
 	*out = *in
@@ -165,11 +321,41 @@ func (in *Rules) DeepCopyInto(out *Rules) {
 		*out = make([]Namespace, len(*in))
 		copy(*out, *in)
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WorkloadSelector != nil {
+		in, out := &in.WorkloadSelector, &out.WorkloadSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]Window, len(*in))
+		copy(*out, *in)
+	}
+	if in.CatchUp != nil {
+		in, out := &in.CatchUp, &out.CatchUp
+		*out = new(bool)
+		**out = **in
+	}
 	if in.OverrideScaling != nil {
 		in, out := &in.OverrideScaling, &out.OverrideScaling
 		*out = make([]types.ResourceType, len(*in))
 		copy(*out, *in)
 	}
+	if in.DownscaleReplicas != nil {
+		in, out := &in.DownscaleReplicas, &out.DownscaleReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UpscaleReplicas != nil {
+		in, out := &in.UpscaleReplicas, &out.UpscaleReplicas
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rules.
@@ -182,6 +368,21 @@ func (in *Rules) DeepCopy() *Rules {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalableResource) DeepCopyInto(out *ScalableResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalableResource.
+func (in *ScalableResource) DeepCopy() *ScalableResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalableResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Schedule) DeepCopyInto(out *Schedule) {
 	*out = *in
@@ -218,3 +419,18 @@ func (in *TimeRules) DeepCopy() *TimeRules {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Window) DeepCopyInto(out *Window) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Window.
+func (in *Window) DeepCopy() *Window {
+	if in == nil {
+		return nil
+	}
+	out := new(Window)
+	in.DeepCopyInto(out)
+	return out
+}
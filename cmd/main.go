@@ -19,7 +19,9 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -41,7 +43,6 @@ import (
 	"github.com/adalbertjnr/kubetime-scaler/internal/factory"
 	"github.com/adalbertjnr/kubetime-scaler/internal/manager"
 	"github.com/adalbertjnr/kubetime-scaler/internal/store"
-	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
 	"github.com/go-logr/logr"
 	//+kubebuilder:scaffold:imports
 )
@@ -59,12 +60,22 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "state" {
+		if err := runState(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var enableDatabase bool
+	var enableWebhooks bool
+	var eventsRetention time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -76,6 +87,10 @@ func main() {
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.BoolVar(&enableDatabase, "database", false,
 		"If set, the program will persist a database store in /data/db, which means the use must persist it using the deployment")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"If set, the validating webhook for Downscaler objects will be served. It requires the webhook serving certificates to be mounted")
+	flag.DurationVar(&eventsRetention, "events-retention", 30*24*time.Hour,
+		"How long the scaling history is kept by the sqlite, postgres, mysql and memory stores. Set to 0 to keep it forever")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -119,22 +134,28 @@ func main() {
 
 	apiClient := client.NewAPIClient(mgr.GetClient())
 
-	dbConfig := db.Config{
-		Driver: utils.LookupString(os.Getenv("DB_DRIVER"), "memory_store"),
-		DSN:    utils.LookupString(os.Getenv("DB_ADDR"), ""),
+	dbConfig, err := db.ConfigFromEnv()
+	if err != nil {
+		setupLog.Error(err, "invalid database configuration")
+		os.Exit(1)
+	}
+	// the secret is read without the cache, which would need to watch every secret of the cluster.
+	if secretCredentials := db.SecretCredentialsFromEnv(mgr.GetAPIReader()); secretCredentials != nil {
+		dbConfig.Credentials = secretCredentials
 	}
 
 	{
 		logger = ctrl.Log.WithValues("controller", "downscaler", "controllerGroup", "downscaler.go")
-		storeClient = store.New(logger, enableDatabase, dbConfig)
+		storeClient = store.New(logger, enableDatabase, dbConfig, mgr.GetClient(), mgr.GetAPIReader())
 		scalerFactory = factory.NewScalerFactory(apiClient, storeClient, logger)
 	}
 
-	downscalerScheduler := (&manager.Downscaler{}).
+	downscalerScheduler := manager.NewScheduler((&manager.Downscaler{}).
 		Client(apiClient).
+		APIReader(mgr.GetAPIReader()).
 		Factory(scalerFactory).
 		Persistence(storeClient).
-		Logger(logger)
+		Logger(logger))
 
 	if err = (&controller.DownscalerReconciler{
 		Client:              mgr.GetClient(),
@@ -145,8 +166,21 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Downscaler")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = downscalergov1alpha1.SetupDownscalerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Downscaler")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
+	if storeClient != nil && eventsRetention > 0 {
+		if err := mgr.Add(store.NewEventsPurger(logger, storeClient.ScalingOperation, eventsRetention, time.Hour)); err != nil {
+			setupLog.Error(err, "unable to set up scaling events purge")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
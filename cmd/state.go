@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/db"
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/go-logr/logr"
+)
+
+const stateUsage = `usage: kubetime-scaler state export [--format json|yaml] [--namespace ns] [--output file]
+       kubetime-scaler state import [--input file]
+
+The store is configured with DB_DRIVER and DB_ADDR, the same as the manager.`
+
+// runState exports the records of the configured store to a json or yaml document, or imports
+// one into it, so the saved replicas survive a change of DB_DRIVER or of cluster.
+func runState(args []string) error {
+	if len(args) == 0 {
+		return errors.New(stateUsage)
+	}
+
+	var (
+		format    string
+		namespace string
+		output    string
+		input     string
+	)
+
+	fs := flag.NewFlagSet("state "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "export":
+		fs.StringVar(&format, "format", store.StateFormatJSON, "The format of the exported state, json or yaml")
+		fs.StringVar(&namespace, "namespace", "", "Export only the records of this namespace")
+		fs.StringVar(&output, "output", "-", "The file the state is written to, - for stdout")
+	case "import":
+		fs.StringVar(&input, "input", "-", "The file the state is read from, - for stdin. Both json and yaml are accepted")
+	default:
+		return fmt.Errorf("unknown state command %q\n%s", args[0], stateUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	ctrl.SetLogger(zap.New(zap.WriteTo(os.Stderr)))
+	logger := ctrl.Log.WithValues("command", "state")
+
+	persistence, err := newStateStore(logger)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := persistence.ScalingOperation.Bootstrap(ctx); err != nil {
+		return fmt.Errorf("bootstrapping the store: %w", err)
+	}
+
+	if args[0] == "export" {
+		state, err := store.ExportState(ctx, persistence.ScalingOperation, store.ScalingOperationFilter{NamespaceName: namespace})
+		if err != nil {
+			return fmt.Errorf("exporting the state: %w", err)
+		}
+
+		w := io.Writer(os.Stdout)
+		if output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := store.EncodeState(w, state, format); err != nil {
+			return err
+		}
+
+		logger.Info("state", "exported records", len(state.ScalingOperations))
+		return nil
+	}
+
+	r := io.Reader(os.Stdin)
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	state, err := store.DecodeState(r)
+	if err != nil {
+		return fmt.Errorf("decoding the state: %w", err)
+	}
+
+	imported, err := store.ImportState(ctx, persistence.ScalingOperation, state)
+	if err != nil {
+		return fmt.Errorf("imported %d of %d records: %w", imported, len(state.ScalingOperations), err)
+	}
+
+	logger.Info("state", "imported records", imported)
+	return nil
+}
+
+// newStateStore builds the store of DB_DRIVER. The kubernetes client is only created for the
+// drivers keeping their records in the cluster and for DB_SECRET_NAME, the sql drivers run
+// without a kubeconfig otherwise.
+func newStateStore(logger logr.Logger) (*store.Persistence, error) {
+	dbConfig, err := db.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var k8sClient ctrlclient.Client
+	driver := strings.ToLower(dbConfig.Driver)
+	if driver == "annotations" || driver == "configmap" || os.Getenv("DB_SECRET_NAME") != "" {
+		config, err := ctrl.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		if k8sClient, err = ctrlclient.New(config, ctrlclient.Options{Scheme: scheme}); err != nil {
+			return nil, err
+		}
+	}
+
+	if secretCredentials := db.SecretCredentialsFromEnv(k8sClient); secretCredentials != nil {
+		dbConfig.Credentials = secretCredentials
+	}
+
+	persistence := store.New(logger, true, dbConfig, k8sClient, k8sClient)
+	if persistence.Volatile {
+		return nil, fmt.Errorf("DB_DRIVER %q does not keep any state, expected one of sqlite, postgres, mysql, annotations or configmap", dbConfig.Driver)
+	}
+
+	return persistence, nil
+}
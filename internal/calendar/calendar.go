@@ -0,0 +1,119 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+)
+
+// Event is a period of time, from Start to End excluded, repeated every Interval years when Yearly.
+type Event struct {
+	Summary    string
+	Start, End time.Time
+
+	Yearly   bool
+	Interval int
+	// Count and Until bound the occurrences of a yearly event when set.
+	Count int
+	Until time.Time
+}
+
+// Covers reports whether at falls inside the event or inside one of its occurrences.
+func (e Event) Covers(at time.Time) bool {
+	if !e.Yearly {
+		return !at.Before(e.Start) && at.Before(e.End)
+	}
+
+	interval := e.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	// an occurrence covering at starts in the year of at or, running over new year, the year before.
+	years := at.Year() - e.Start.Year()
+	for k := (years-1)/interval - 1; k <= years/interval+1; k++ {
+		if k < 0 || e.Count > 0 && k >= e.Count {
+			continue
+		}
+
+		start := e.Start.AddDate(k*interval, 0, 0)
+		if !e.Until.IsZero() && start.After(e.Until) {
+			continue
+		}
+		if !at.Before(start) && at.Before(start.Add(e.End.Sub(e.Start))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Exception is an event of a calendar with the action it applies to the rules.
+type Exception struct {
+	Event
+	Action downscalergov1alpha1.CalendarAction
+}
+
+// FromSpec converts the exceptions listed in a calendar, each one covering its days from midnight
+// to midnight in location.
+func FromSpec(exceptions []downscalergov1alpha1.CalendarException, location *time.Location) ([]Exception, error) {
+	var converted []Exception
+	for _, exception := range exceptions {
+		start, end, err := ParseDates(exception.Date, exception.EndDate, location)
+		if err != nil {
+			return nil, err
+		}
+
+		converted = append(converted, Exception{
+			Event:  Event{Summary: exception.Description, Start: start, End: end},
+			Action: exception.Action,
+		})
+	}
+	return converted, nil
+}
+
+// ParseDates returns the midnight starting date and the one ending endDate, which defaults to date.
+func ParseDates(date, endDate string, location *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(time.DateOnly, date, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", date)
+	}
+
+	last := start
+	if endDate != "" {
+		if last, err = time.ParseInLocation(time.DateOnly, endDate, location); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q: expected YYYY-MM-DD", endDate)
+		}
+		if last.Before(start) {
+			return time.Time{}, time.Time{}, fmt.Errorf("end date %s is before date %s", endDate, date)
+		}
+	}
+
+	return start, last.AddDate(0, 0, 1), nil
+}
+
+// Find returns the action of the first exception covering at.
+func Find(exceptions []Exception, at time.Time) (Exception, bool) {
+	for _, exception := range exceptions {
+		if exception.Covers(at) {
+			return exception, true
+		}
+	}
+	return Exception{}, false
+}
+
+// Allows reports whether an operation runs under the action of an exception: a forced down day
+// only runs downscales, a forced up day only upscales and a skipped day none of them.
+func Allows(action downscalergov1alpha1.CalendarAction, operation types.ScalingOperation) bool {
+	switch action {
+	case downscalergov1alpha1.CalendarActionForceDown:
+		return operation == types.OperationDownscale
+	case downscalergov1alpha1.CalendarActionForceUp:
+		return operation == types.OperationUpscale
+	case downscalergov1alpha1.CalendarActionSkip:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package calendar_test
+
+import (
+	"testing"
+	"time"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/calendar"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindException(t *testing.T) {
+	location, _ := time.LoadLocation("America/Sao_Paulo")
+
+	exceptions, err := calendar.FromSpec([]downscalergov1alpha1.CalendarException{
+		{Date: "2024-12-24", EndDate: "2024-12-31", Action: downscalergov1alpha1.CalendarActionForceDown, Description: "holidays"},
+		{Date: "2024-12-20", EndDate: "2024-12-26", Action: downscalergov1alpha1.CalendarActionForceUp, Description: "release"},
+	}, location)
+	if err != nil {
+		t.Fatalf("converting exceptions failed: %v", err)
+	}
+
+	testCases := []struct {
+		at       time.Time
+		expected string
+	}{
+		{at: time.Date(2024, time.December, 19, 23, 59, 0, 0, location)},
+		{at: time.Date(2024, time.December, 20, 0, 0, 0, 0, location), expected: "release"},
+		// the first exception wins where both cover the day.
+		{at: time.Date(2024, time.December, 24, 8, 0, 0, 0, location), expected: "holidays"},
+		{at: time.Date(2024, time.December, 31, 23, 0, 0, 0, location), expected: "holidays"},
+		// midnight in UTC is still the 31st in the time zone of the rule.
+		{at: time.Date(2025, time.January, 1, 2, 0, 0, 0, time.UTC), expected: "holidays"},
+		{at: time.Date(2025, time.January, 1, 3, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		exception, _ := calendar.Find(exceptions, tc.at)
+		assert.Equal(t, tc.expected, exception.Summary, tc.at.String())
+	}
+
+	_, err = calendar.FromSpec([]downscalergov1alpha1.CalendarException{{Date: "2024-12-31", EndDate: "2024-12-24"}}, location)
+	assert.Error(t, err)
+}
+
+func TestAllows(t *testing.T) {
+	assert.True(t, calendar.Allows(downscalergov1alpha1.CalendarActionForceDown, types.OperationDownscale))
+	assert.False(t, calendar.Allows(downscalergov1alpha1.CalendarActionForceDown, types.OperationUpscale))
+	assert.False(t, calendar.Allows(downscalergov1alpha1.CalendarActionForceUp, types.OperationDownscale))
+	assert.True(t, calendar.Allows(downscalergov1alpha1.CalendarActionForceUp, types.OperationUpscale))
+	assert.False(t, calendar.Allows(downscalergov1alpha1.CalendarActionSkip, types.OperationUpscale))
+	assert.True(t, calendar.Allows("", types.OperationUpscale))
+}
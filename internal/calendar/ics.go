@@ -0,0 +1,261 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405"
+)
+
+var icsDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// icsProperty is one content line of an iCalendar file, such as DTSTART;VALUE=DATE:20241225.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICS returns the events of iCalendar content. All-day events and times without a time zone
+// are read in location, as are times whose TZID is not a known time zone. Cancelled events are
+// left out and, of the recurrence rules, only the yearly ones are supported. An event that can't be
+// read, such as a weekly one, is left out too: the error joins the reasons of every event skipped,
+// along with the events that were read.
+func ParseICS(content string, location *time.Location) ([]Event, error) {
+	var (
+		events    []Event
+		errs      []error
+		event     *Event
+		eventErr  error
+		cancelled bool
+		hasEnd    bool
+	)
+
+	for number, line := range unfoldICS(content) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		property, err := parseICSProperty(line)
+		if err != nil {
+			err = fmt.Errorf("line %d: %v", number+1, err)
+			if event == nil {
+				errs = append(errs, err)
+			} else if eventErr == nil {
+				eventErr = err
+			}
+			continue
+		}
+
+		switch {
+		case property.name == "BEGIN" && strings.EqualFold(property.value, "VEVENT"):
+			if event != nil {
+				errs = append(errs, fmt.Errorf("event %q has no END:VEVENT", event.Summary))
+			}
+			event, eventErr, cancelled, hasEnd = &Event{}, nil, false, false
+			continue
+		case property.name == "END" && strings.EqualFold(property.value, "VEVENT"):
+			if event == nil {
+				errs = append(errs, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", number+1))
+				continue
+			}
+			if eventErr == nil && event.Start.IsZero() {
+				eventErr = errors.New("no DTSTART")
+			}
+			switch {
+			case eventErr != nil:
+				errs = append(errs, fmt.Errorf("event %q skipped: %v", event.Summary, eventErr))
+			case !cancelled:
+				if !hasEnd {
+					// an all-day event without an end lasts its day, a timed one has no length.
+					event.End = event.Start
+					if event.Start.Equal(midnight(event.Start)) {
+						event.End = event.Start.AddDate(0, 0, 1)
+					}
+				}
+				events = append(events, *event)
+			}
+			event = nil
+			continue
+		}
+
+		if event == nil {
+			continue
+		}
+		if property.name == "SUMMARY" {
+			event.Summary = property.value
+			continue
+		}
+		// the summary still names a skipped event in the error.
+		if eventErr != nil {
+			continue
+		}
+
+		switch property.name {
+		case "STATUS":
+			cancelled = strings.EqualFold(property.value, "CANCELLED")
+		case "DTSTART":
+			event.Start, eventErr = parseICSTime(property, location)
+		case "DTEND":
+			event.End, eventErr = parseICSTime(property, location)
+			hasEnd = true
+		case "DURATION":
+			var duration time.Duration
+			duration, eventErr = parseICSDuration(property.value)
+			event.End = event.Start.Add(duration)
+			hasEnd = true
+		case "RRULE":
+			eventErr = parseICSRecurrence(event, property.value, location)
+		}
+	}
+
+	if event != nil {
+		errs = append(errs, fmt.Errorf("event %q has no END:VEVENT", event.Summary))
+	}
+
+	return events, errors.Join(errs...)
+}
+
+// unfoldICS joins the lines continued on the next one, which start with a space or a tab.
+func unfoldICS(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+func parseICSProperty(line string) (icsProperty, error) {
+	// the value starts at the first colon outside of a quoted parameter.
+	quoted, separator := false, -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			separator = i
+			break
+		}
+	}
+	if separator < 0 {
+		return icsProperty{}, fmt.Errorf("invalid content line %q", line)
+	}
+
+	parts := strings.Split(line[:separator], ";")
+	property := icsProperty{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[separator+1:],
+	}
+	for _, param := range parts[1:] {
+		if name, value, found := strings.Cut(param, "="); found {
+			property.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return property, nil
+}
+
+// parseICSTime reads a DATE value when the property says so, otherwise the first layout the value
+// matches: a UTC time, a local time, in the TZID of the property when set, or a bare date as some
+// calendars write all-day events and UNTIL without VALUE=DATE.
+func parseICSTime(property icsProperty, location *time.Location) (time.Time, error) {
+	value := property.value
+	if strings.EqualFold(property.params["VALUE"], "DATE") {
+		t, err := time.ParseInLocation(icsDateLayout, value, location)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s date %q", property.name, value)
+		}
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation(icsDateTimeLayout+"Z", value, time.UTC); err == nil {
+		return t, nil
+	}
+
+	if tzid := property.params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			location = tz
+		}
+	}
+	if t, err := time.ParseInLocation(icsDateTimeLayout, value, location); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(icsDateLayout, value, location); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid %s time %q", property.name, value)
+}
+
+func parseICSDuration(value string) (time.Duration, error) {
+	match := icsDuration.FindStringSubmatch(strings.TrimPrefix(value, "+"))
+	if match == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid DURATION %q", value)
+	}
+
+	var duration time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+1] == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(match[i+1])
+		duration += time.Duration(n) * unit
+	}
+	return duration, nil
+}
+
+func parseICSRecurrence(event *Event, value string, location *time.Location) error {
+	for _, part := range strings.Split(value, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			if !strings.EqualFold(value, "YEARLY") {
+				return fmt.Errorf("unsupported recurrence FREQ=%s, only yearly events are supported", value)
+			}
+			event.Yearly = true
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval <= 0 {
+				return fmt.Errorf("invalid recurrence INTERVAL=%s", value)
+			}
+			event.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count <= 0 {
+				return fmt.Errorf("invalid recurrence COUNT=%s", value)
+			}
+			event.Count = count
+		case "UNTIL":
+			until, err := parseICSTime(icsProperty{name: "UNTIL", value: value}, location)
+			if err != nil {
+				return err
+			}
+			event.Until = until
+		case "BYMONTH", "BYMONTHDAY", "WKST":
+			// the month and day of DTSTART, which is what a yearly holiday repeats.
+		default:
+			return fmt.Errorf("unsupported recurrence part %s", part)
+		}
+	}
+
+	if !event.Yearly {
+		return fmt.Errorf("recurrence %q has no FREQ", value)
+	}
+	return nil
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
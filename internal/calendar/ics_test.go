@@ -0,0 +1,149 @@
+package calendar_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/calendar"
+	"github.com/stretchr/testify/assert"
+)
+
+const holidays = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"DTSTART;VALUE=DATE:20241225\r\n" +
+	"DTEND;VALUE=DATE:20241226\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Company shutdown\r\n" +
+	"  week\r\n" +
+	"DTSTART;VALUE=DATE:20241227\r\n" +
+	"DURATION:P1W\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Release night\r\n" +
+	"DTSTART;TZID=\"America/Sao_Paulo\":20240610T180000\r\n" +
+	"DTEND:20240611T060000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Cancelled offsite\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART;VALUE=DATE:20240301\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Berlin")
+
+	events, err := calendar.ParseICS(holidays, location)
+	if err != nil {
+		t.Fatalf("parse ics failed: %v", err)
+	}
+
+	var summaries []string
+	for _, event := range events {
+		summaries = append(summaries, event.Summary)
+	}
+	assert.Equal(t, []string{"Christmas", "Company shutdown week", "Release night"}, summaries)
+
+	testCases := []struct {
+		at       time.Time
+		expected string
+	}{
+		{at: time.Date(2024, time.December, 25, 10, 0, 0, 0, location), expected: "Christmas"},
+		{at: time.Date(2030, time.December, 25, 0, 0, 0, 0, location), expected: "Christmas"},
+		{at: time.Date(2024, time.December, 26, 0, 0, 0, 0, location)},
+		{at: time.Date(2025, time.January, 2, 23, 59, 0, 0, location), expected: "Company shutdown week"},
+		{at: time.Date(2025, time.January, 3, 0, 0, 0, 0, location)},
+		{at: time.Date(2024, time.June, 10, 23, 0, 0, 0, time.UTC), expected: "Release night"},
+		{at: time.Date(2024, time.June, 11, 6, 0, 0, 0, time.UTC)},
+		{at: time.Date(2024, time.March, 1, 12, 0, 0, 0, location)},
+	}
+
+	for _, tc := range testCases {
+		var covered string
+		for _, event := range events {
+			if event.Covers(tc.at) {
+				covered = event.Summary
+				break
+			}
+		}
+		assert.Equal(t, tc.expected, covered, tc.at.String())
+	}
+}
+
+func TestParseICSErrors(t *testing.T) {
+	testCases := map[string]string{
+		"weekly recurrence":  "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101\nRRULE:FREQ=WEEKLY\nEND:VEVENT\n",
+		"missing start":      "BEGIN:VEVENT\nSUMMARY:nothing\nEND:VEVENT\n",
+		"invalid date":       "BEGIN:VEVENT\nDTSTART;VALUE=DATE:2024-01-01\nEND:VEVENT\n",
+		"unterminated":       "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101\n",
+		"line without colon": "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101\nSUMMARY Holiday\nEND:VEVENT\n",
+		"time as date":       "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101T000000\nEND:VEVENT\n",
+		"truncated time":     "BEGIN:VEVENT\nDTSTART:20240101T0900\nEND:VEVENT\n",
+		"invalid month":      "BEGIN:VEVENT\nDTSTART:20241301\nEND:VEVENT\n",
+		"trailing garbage":   "BEGIN:VEVENT\nDTSTART:20240101T090000ZZ\nEND:VEVENT\n",
+		"invalid until":      "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101\nRRULE:FREQ=YEARLY;UNTIL=2030\nEND:VEVENT\n",
+	}
+
+	for name, content := range testCases {
+		t.Run(name, func(t *testing.T) {
+			events, err := calendar.ParseICS(content, time.UTC)
+			assert.Error(t, err)
+			assert.Empty(t, events)
+		})
+	}
+}
+
+func TestParseICSSkipsUnsupportedEvents(t *testing.T) {
+	content := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Weekly standup",
+		"DTSTART:20240101T090000",
+		"RRULE:FREQ=WEEKLY",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20240101",
+		"RRULE:FREQ=MONTHLY;BYDAY=1MO",
+		"SUMMARY:Monthly review",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:New year",
+		"DTSTART;VALUE=DATE:20240101",
+		"RRULE:FREQ=YEARLY",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\n")
+
+	events, err := calendar.ParseICS(content, time.UTC)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `"Weekly standup"`)
+		assert.Contains(t, err.Error(), `"Monthly review"`)
+	}
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "New year", events[0].Summary)
+	}
+}
+
+func TestParseICSYearlyCount(t *testing.T) {
+	content := strings.Join([]string{
+		"BEGIN:VEVENT",
+		"SUMMARY:Anniversary",
+		"DTSTART;VALUE=DATE:20240501",
+		"RRULE:FREQ=YEARLY;INTERVAL=2;COUNT=2",
+		"END:VEVENT",
+	}, "\n")
+
+	events, err := calendar.ParseICS(content, time.UTC)
+	if err != nil {
+		t.Fatalf("parse ics failed: %v", err)
+	}
+
+	for year, expected := range map[int]bool{2024: true, 2025: false, 2026: true, 2028: false} {
+		assert.Equal(t, expected, events[0].Covers(time.Date(year, time.May, 1, 12, 0, 0, 0, time.UTC)), year)
+	}
+}
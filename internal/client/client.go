@@ -2,13 +2,18 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
 	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	v2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -26,10 +31,10 @@ func NewAPIClient(c client.Client) *APIClient {
 	}
 }
 
-func (c *APIClient) GetNamespaces() (*v1.NamespaceList, error) {
+func (c *APIClient) GetNamespaces(selector labels.Selector) (*v1.NamespaceList, error) {
 	var namespaces v1.NamespaceList
 
-	if err := c.Client.List(c.ctx, &namespaces); err != nil {
+	if err := c.Client.List(c.ctx, &namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
 		return nil, err
 	}
 
@@ -37,24 +42,66 @@ func (c *APIClient) GetNamespaces() (*v1.NamespaceList, error) {
 }
 
 func (c *APIClient) Patch(replicas int, object any) error {
-	patchOpts := client.Merge
 	replicaCount := int32(replicas)
 
 	switch value := object.(type) {
 	case *appsv1.Deployment:
 		value.Spec.Replicas = &replicaCount
-		return c.Client.Patch(c.ctx, value, patchOpts)
+		return c.patchSpec(value, map[string]any{"replicas": replicaCount})
 	case *appsv1.StatefulSet:
 		value.Spec.Replicas = &replicaCount
-		return c.Client.Patch(c.ctx, value, patchOpts)
+		return c.patchSpec(value, map[string]any{"replicas": replicaCount})
 	case *v2.HorizontalPodAutoscaler:
 		value.Spec.MinReplicas = &replicaCount
-		return c.Client.Patch(c.ctx, value, patchOpts)
+		return c.patchSpec(value, map[string]any{"minReplicas": replicaCount})
 	default:
 		return fmt.Errorf("resource type for patching found")
 	}
 }
 
+func (c *APIClient) PatchHPA(minReplicas, maxReplicas int, hpa *v2.HorizontalPodAutoscaler) error {
+	minReplicaCount := int32(minReplicas)
+	hpa.Spec.MinReplicas = &minReplicaCount
+	hpa.Spec.MaxReplicas = int32(maxReplicas)
+
+	return c.patchSpec(hpa, map[string]any{"minReplicas": minReplicaCount, "maxReplicas": hpa.Spec.MaxReplicas})
+}
+
+func (c *APIClient) PatchSuspend(suspend bool, cronJob *batchv1.CronJob) error {
+	cronJob.Spec.Suspend = &suspend
+
+	return c.patchSpec(cronJob, map[string]any{"suspend": suspend})
+}
+
+// patchSpec sends only the given spec fields, so the patch neither carries the resourceVersion of
+// the listed object nor overwrites metadata changed since, like the annotations store records.
+func (c *APIClient) patchSpec(object client.Object, spec map[string]any) error {
+	patch, err := json.Marshal(map[string]any{"spec": spec})
+	if err != nil {
+		return err
+	}
+
+	return c.Client.Patch(c.ctx, object, client.RawPatch(types.MergePatchType, patch))
+}
+
+// GetScale reads the scale subresource of any object implementing it.
+func (c *APIClient) GetScale(object client.Object) (*autoscalingv1.Scale, error) {
+	scale := &autoscalingv1.Scale{}
+	if err := c.Client.SubResource("scale").Get(c.ctx, object, scale); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+// UpdateScale clears the resourceVersion of the scale read before, so the update does not conflict
+// with the annotations the store wrote on the object since, like patchSpec.
+func (c *APIClient) UpdateScale(replicas int, object client.Object, scale *autoscalingv1.Scale) error {
+	scale.Spec.Replicas = int32(replicas)
+	scale.ResourceVersion = ""
+
+	return c.Client.SubResource("scale").Update(c.ctx, object, client.WithSubResourceBody(scale))
+}
+
 func (c *APIClient) Get(namespace string, resource any, name ...string) error {
 	listOpts := &client.ListOptions{Namespace: namespace}
 
@@ -63,21 +110,33 @@ func (c *APIClient) Get(namespace string, resource any, name ...string) error {
 		return c.Client.Get(c.ctx, types.NamespacedName{Name: name[0], Namespace: namespace}, value)
 	case *appsv1.StatefulSet:
 		return c.Client.Get(c.ctx, types.NamespacedName{Name: name[0], Namespace: namespace}, value)
+	case *v2.HorizontalPodAutoscaler:
+		return c.Client.Get(c.ctx, types.NamespacedName{Name: name[0], Namespace: namespace}, value)
+	case *batchv1.CronJob:
+		return c.Client.Get(c.ctx, types.NamespacedName{Name: name[0], Namespace: namespace}, value)
+	case *unstructured.Unstructured:
+		return c.Client.Get(c.ctx, types.NamespacedName{Name: name[0], Namespace: namespace}, value)
 	case *appsv1.DeploymentList:
 		return c.Client.List(c.ctx, value, listOpts)
 	case *appsv1.StatefulSetList:
 		return c.Client.List(c.ctx, value, listOpts)
 	case *v2.HorizontalPodAutoscalerList:
 		return c.Client.List(c.ctx, value, listOpts)
+	case *batchv1.CronJobList:
+		return c.Client.List(c.ctx, value, listOpts)
+	case *unstructured.UnstructuredList:
+		return c.Client.List(c.ctx, value, listOpts)
 	default:
 		return fmt.Errorf("the resource type was not found for get")
 	}
 }
 
 func (c *APIClient) GetDownscaler(downscalerObject downscalergov1alpha1.Downscaler) (downscaler downscalergov1alpha1.Downscaler, err error) {
-	namespace, err := utils.GetNamespace()
-	if err != nil {
-		namespace = "kubetime-scaler"
+	namespace := downscalerObject.Namespace
+	if namespace == "" {
+		if namespace, err = utils.GetNamespace(); err != nil {
+			namespace = "kubetime-scaler"
+		}
 	}
 
 	if err := c.Client.Get(context.Background(), types.NamespacedName{
@@ -89,3 +148,12 @@ func (c *APIClient) GetDownscaler(downscalerObject downscalergov1alpha1.Downscal
 
 	return downscaler, nil
 }
+
+func (c *APIClient) PatchDownscalerStatus(downscalerObject downscalergov1alpha1.Downscaler, status downscalergov1alpha1.DownscalerStatus) error {
+	patch, err := json.Marshal(map[string]any{"status": status})
+	if err != nil {
+		return err
+	}
+
+	return c.Client.Status().Patch(c.ctx, &downscalerObject, client.RawPatch(types.MergePatchType, patch))
+}
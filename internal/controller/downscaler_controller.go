@@ -19,27 +19,35 @@ package controller
 import (
 	"context"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
 	"github.com/adalbertjnr/kubetime-scaler/internal/manager"
 	"github.com/go-logr/logr"
 )
 
+// downscalerFinalizer keeps the object around until the workloads it downscaled are restored.
+const downscalerFinalizer = "downscaler.go/finalizer"
+
 // DownscalerReconciler reconciles a Downscaler object
 type DownscalerReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Logger logr.Logger
 
-	DownscalerScheduler *manager.Downscaler
+	DownscalerScheduler *manager.Scheduler
 }
 
 //+kubebuilder:rbac:groups=downscaler.go,resources=downscalers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=downscaler.go,resources=downscalers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=downscaler.go,resources=downscalers/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -51,27 +59,57 @@ type DownscalerReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.3/pkg/reconcile
 func (r *DownscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	dc := r.DownscalerScheduler
-
 	var app downscalergov1alpha1.Downscaler
 	if err := r.Get(ctx, req.NamespacedName, &app); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.DownscalerScheduler.Remove(req.NamespacedName)
+			r.Logger.Info("reconcile", "name", req.Name, "namespace", req.Namespace, "status", "deleted")
+			return ctrl.Result{}, nil
+		}
 		ctrl.Log.Error(err, "reconcile", "error", err)
 		return ctrl.Result{}, err
 	}
 
-	r.Logger.Info("reconcile", "kind", app.Kind, "name", app.Name, "status", "updated")
+	if !app.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, app)
+	}
+
+	if controllerutil.AddFinalizer(&app, downscalerFinalizer) {
+		if err := r.Update(ctx, &app); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	r.Logger.Info("reconcile", "kind", app.Kind, "name", app.Name, "namespace", app.Namespace, "status", "updated")
+
+	return r.DownscalerScheduler.Schedule(ctx, app)
+}
 
-	if valid := dc.Add(ctx, app).
-		Validate(); !valid {
+func (r *DownscalerReconciler) finalize(ctx context.Context, app downscalergov1alpha1.Downscaler) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(&app, downscalerFinalizer) {
 		return ctrl.Result{}, nil
 	}
 
-	return dc.Run()
+	if err := r.DownscalerScheduler.Finalize(ctx, app); err != nil {
+		r.Logger.Error(err, "finalizer", "name", app.Name, "restoring replicas error", err)
+		return ctrl.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(&app, downscalerFinalizer)
+	if err := r.Update(ctx, &app); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.Logger.Info("reconcile", "kind", app.Kind, "name", app.Name, "namespace", app.Namespace, "status", "finalized")
+
+	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Only generation changes trigger a reconcile, otherwise every status patch
+// made by the scheduler would reschedule the cron entries.
 func (r *DownscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&downscalergov1alpha1.Downscaler{}).
+		For(&downscalergov1alpha1.Downscaler{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
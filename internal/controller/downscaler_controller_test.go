@@ -106,14 +106,15 @@ var _ = Describe("Downscaler Controller", func() {
 				Driver: utils.LookupString(os.Getenv("DB_DRIVER"), "memory_store"),
 				DSN:    utils.LookupString(os.Getenv("DSN"), ""),
 			}
-			storeClient := store.New(logr.Logger{}, false, dbConfig)
+			storeClient := store.New(logr.Logger{}, false, dbConfig, nil, nil)
 			scalerFactory := factory.NewScalerFactory(apiClient, storeClient, logr.Logger{})
 
-			downscalerScheduler := (&manager.Downscaler{}).
+			downscalerScheduler := manager.NewScheduler((&manager.Downscaler{}).
 				Client(apiClient).
+				APIReader(k8sClient).
 				Factory(scalerFactory).
 				Persistence(storeClient).
-				Logger(logr.Logger{})
+				Logger(logr.Logger{}))
 
 			By("Reconciling the created resource")
 			controllerReconciler := &DownscalerReconciler{
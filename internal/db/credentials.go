@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
+	"github.com/lib/pq"
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The keys read from the credentials secret, or the file names under the credentials directory.
+// Mounting the secret as a volume gives one file per key.
+const (
+	credentialHost        = "host"
+	credentialPort        = "port"
+	credentialUser        = "user"
+	credentialUsername    = "username"
+	credentialPassword    = "password"
+	credentialDBName      = "dbname"
+	credentialDatabase    = "database"
+	credentialSSLMode     = "sslmode"
+	credentialCACert      = "ca.crt"
+	credentialSSLRootCert = "sslrootcert"
+)
+
+// CredentialsSource returns the connection settings of postgres. It is read again for every new
+// connection, so credentials rotated in the secret or in the mounted files are used by the next
+// connection of the pool without a restart.
+type CredentialsSource interface {
+	Credentials(ctx context.Context) (map[string]string, error)
+}
+
+// TLSConfig holds the settings of DB_SSLMODE and DB_SSLROOTCERT, taking precedence over the ones
+// found in the credentials.
+type TLSConfig struct {
+	SSLMode     string
+	SSLRootCert string
+}
+
+// FileCredentials reads one file per setting from a directory, like a mounted secret.
+type FileCredentials struct {
+	dir string
+}
+
+func NewFileCredentials(dir string) *FileCredentials {
+	return &FileCredentials{dir: dir}
+}
+
+func (fc *FileCredentials) Credentials(ctx context.Context) (map[string]string, error) {
+	entries, err := os.ReadDir(fc.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make(map[string]string)
+	for _, entry := range entries {
+		// the files of a mounted secret are symlinks into the ..data directory, swapped on rotation.
+		if strings.HasPrefix(entry.Name(), "..") || entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(fc.dir, entry.Name())
+		if entry.Name() == credentialCACert {
+			credentials[credentialSSLRootCert] = path
+			continue
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		credentials[entry.Name()] = strings.TrimSpace(string(value))
+	}
+
+	return credentials, nil
+}
+
+// SecretCredentials reads the settings from the keys of a secret. The reader should not be backed
+// by the cache of the manager, which would watch every secret of the cluster.
+type SecretCredentials struct {
+	reader client.Reader
+	key    k8stypes.NamespacedName
+}
+
+func NewSecretCredentials(reader client.Reader, namespace, name string) *SecretCredentials {
+	return &SecretCredentials{reader: reader, key: k8stypes.NamespacedName{Namespace: namespace, Name: name}}
+}
+
+func (sc *SecretCredentials) Credentials(ctx context.Context) (map[string]string, error) {
+	var secret corev1.Secret
+	if err := sc.reader.Get(ctx, sc.key, &secret); err != nil {
+		return nil, fmt.Errorf("reading secret %s: %w", sc.key, err)
+	}
+
+	credentials := make(map[string]string)
+	for key, value := range secret.Data {
+		if key == credentialCACert {
+			// lib/pq reads an inline certificate instead of a file when sslinline is set.
+			credentials[credentialSSLRootCert] = string(value)
+			credentials["sslinline"] = "true"
+			continue
+		}
+		credentials[key] = strings.TrimSpace(string(value))
+	}
+
+	return credentials, nil
+}
+
+// postgresDSN builds a key/value connection string, quoting every value so passwords and inline
+// certificates need no escaping by the user.
+func postgresDSN(credentials map[string]string, tls TLSConfig) (string, error) {
+	settings := map[string]string{}
+	for key, value := range credentials {
+		switch key {
+		case credentialUsername:
+			key = credentialUser
+		case credentialDatabase:
+			key = credentialDBName
+		}
+		settings[key] = value
+	}
+
+	if tls.SSLMode != "" {
+		settings[credentialSSLMode] = tls.SSLMode
+	}
+	if tls.SSLRootCert != "" {
+		settings[credentialSSLRootCert] = tls.SSLRootCert
+		delete(settings, "sslinline")
+	}
+
+	if settings[credentialHost] == "" || settings[credentialUser] == "" {
+		return "", errors.New("the postgres credentials must have at least a host and a user")
+	}
+
+	var keys []string
+	for key := range settings {
+		switch key {
+		case credentialHost, credentialPort, credentialUser, credentialPassword, credentialDBName,
+			credentialSSLMode, credentialSSLRootCert, "sslinline":
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(settings[key])
+		pairs = append(pairs, key+"='"+value+"'")
+	}
+
+	return strings.Join(pairs, " "), nil
+}
+
+// rotatingConnector resolves the credentials on every new connection of the pool.
+type rotatingConnector struct {
+	source CredentialsSource
+	tls    TLSConfig
+}
+
+func (rc *rotatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	credentials, err := rc.source.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn, err := postgresDSN(credentials, rc.tls)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(ctx)
+}
+
+func (rc *rotatingConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// SecretCredentialsFromEnv returns the source of the secret named by DB_SECRET_NAME, in
+// DB_SECRET_NAMESPACE or the namespace of the controller, or nil when no secret is configured.
+func SecretCredentialsFromEnv(reader client.Reader) CredentialsSource {
+	name := os.Getenv("DB_SECRET_NAME")
+	if name == "" {
+		return nil
+	}
+
+	namespace := os.Getenv("DB_SECRET_NAMESPACE")
+	if namespace == "" {
+		var err error
+		if namespace, err = utils.GetNamespace(); err != nil {
+			namespace = "kubetime-scaler"
+		}
+	}
+
+	return NewSecretCredentials(reader, namespace, name)
+}
@@ -1,28 +1,192 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
+	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	"k8s.io/apimachinery/pkg/util/wait"
 	_ "modernc.org/sqlite"
 )
 
 type Config struct {
 	Driver string
 	DSN    string
+
+	// Credentials replaces DSN for postgres, the connection settings are read from it for every
+	// new connection. TLS applies to both.
+	Credentials CredentialsSource
+	TLS         TLSConfig
+
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime size the connection pool, zero values take
+	// the defaults of the driver.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// ConnectBackoff is used on startup, before the health probes are served. The retries add up to
+// a few seconds, bounded by ConnectTimeout, well within the initial delay of the liveness probe:
+// the manager then starts without the database, the jobs are queued until it answers.
+var ConnectBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Steps:    4,
+	Cap:      4 * time.Second,
+}
+
+// ConnectTimeout bounds the startup wait, a ping to an unreachable host can hang on its own.
+const ConnectTimeout = 10 * time.Second
+
+// defaultMaxOpenConns keeps a single connection for sqlite, it allows one writer at a time and a
+// second connection would wait on the lock of the first one.
+func defaultMaxOpenConns(driver string) int {
+	if strings.ToLower(driver) == "sqlite" {
+		return 1
+	}
+	return 10
+}
+
+// Open creates the client and sizes its pool, it does not connect to the database.
+func Open(c Config) (*sql.DB, error) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	switch {
+	case c.Credentials != nil && strings.ToLower(c.Driver) == "postgres":
+		db = sql.OpenDB(&rotatingConnector{source: c.Credentials, tls: c.TLS})
+	case c.Credentials != nil:
+		return nil, fmt.Errorf("credentials from a secret or files are only supported by postgres, not %s", c.Driver)
+	default:
+		if db, err = sql.Open(c.Driver, withTLS(c.Driver, c.DSN, c.TLS)); err != nil {
+			return nil, err
+		}
+	}
+
+	maxOpenConns := c.MaxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = defaultMaxOpenConns(c.Driver)
+	}
+	maxIdleConns := c.MaxIdleConns
+	if maxIdleConns <= 0 || maxIdleConns > maxOpenConns {
+		maxIdleConns = maxOpenConns
+	}
+
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+
+	return db, nil
 }
 
-func MustCreateClient(dbDriver string, c Config) *sql.DB {
-	db, err := sql.Open(c.Driver, c.DSN)
-	if err != nil {
-		panic(err)
+// WaitForConnection pings the database until it answers, sleeping between the attempts as set
+// by the backoff. The last ping error is returned once the backoff runs out of steps.
+func WaitForConnection(ctx context.Context, db *sql.DB, backoff wait.Backoff, onRetry func(attempt int, err error)) error {
+	var (
+		attempt int
+		pingErr error
+	)
+
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		attempt++
+		if pingErr = db.PingContext(ctx); pingErr != nil {
+			if onRetry != nil {
+				onRetry(attempt, pingErr)
+			}
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && pingErr != nil {
+		return pingErr
 	}
 
-	db.SetMaxOpenConns(1)
+	return err
+}
+
+// withTLS sets the TLS options of postgres on a url or key/value dsn, the other drivers take the
+// dsn as is.
+func withTLS(driver, dsn string, tls TLSConfig) string {
+	if strings.ToLower(driver) != "postgres" || (tls.SSLMode == "" && tls.SSLRootCert == "") {
+		return dsn
+	}
+
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		if tls.SSLMode != "" {
+			query.Set("sslmode", tls.SSLMode)
+		}
+		if tls.SSLRootCert != "" {
+			query.Set("sslrootcert", tls.SSLRootCert)
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	// later keys take precedence in a key/value dsn.
+	if tls.SSLMode != "" {
+		dsn += " sslmode='" + tls.SSLMode + "'"
+	}
+	if tls.SSLRootCert != "" {
+		dsn += " sslrootcert='" + tls.SSLRootCert + "'"
+	}
+	return dsn
+}
+
+// ConfigFromEnv reads the store configuration of the manager: DB_DRIVER, DB_ADDR or the files of
+// DB_CREDENTIALS_DIR, the TLS options of DB_SSLMODE and DB_SSLROOTCERT and the pool sizing of
+// DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONN_MAX_LIFETIME. DB_SECRET_NAME is left to the
+// caller, reading the secret needs a kubernetes client.
+func ConfigFromEnv() (Config, error) {
+	c := Config{
+		Driver: utils.LookupString(os.Getenv("DB_DRIVER"), "memory_store"),
+		DSN:    utils.LookupString(os.Getenv("DB_ADDR"), ""),
+		TLS: TLSConfig{
+			SSLMode:     os.Getenv("DB_SSLMODE"),
+			SSLRootCert: os.Getenv("DB_SSLROOTCERT"),
+		},
+	}
+
+	if dir := os.Getenv("DB_CREDENTIALS_DIR"); dir != "" {
+		c.Credentials = NewFileCredentials(dir)
+	}
+
+	var err error
+	if c.MaxOpenConns, err = lookupInt("DB_MAX_OPEN_CONNS"); err != nil {
+		return c, err
+	}
+	if c.MaxIdleConns, err = lookupInt("DB_MAX_IDLE_CONNS"); err != nil {
+		return c, err
+	}
+	if value := os.Getenv("DB_CONN_MAX_LIFETIME"); value != "" {
+		if c.ConnMaxLifetime, err = time.ParseDuration(value); err != nil {
+			return c, fmt.Errorf("DB_CONN_MAX_LIFETIME: %v", err)
+		}
+	}
+
+	return c, nil
+}
+
+func lookupInt(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
 
-	if err := db.Ping(); err != nil {
-		panic(err)
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: expected a positive number, got %q", name, value)
 	}
 
-	return db
+	return n, nil
 }
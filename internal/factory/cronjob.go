@@ -0,0 +1,148 @@
+package factory
+
+import (
+	"context"
+	"errors"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/client"
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// a cronjob has no replicas, its suspend field is saved in the Replicas column instead.
+const (
+	cronJobActive    = 0
+	cronJobSuspended = 1
+)
+
+type ScaleCronJob struct {
+	client *client.APIClient
+	logger logr.Logger
+
+	persistence bool
+	storeClient *store.Persistence
+}
+
+func cronJobSuspendValue(cronJob *batchv1.CronJob) int {
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		return cronJobSuspended
+	}
+	return cronJobActive
+}
+
+// Run suspends the cronjobs on downscale. On upscale the suspend value saved before the downscale
+// is set back, so a cronjob that was already suspended stays suspended. Without persistence the
+// cronjobs are resumed.
+func (sc *ScaleCronJob) Run(downscalerObject downscalergov1alpha1.Downscaler, rule downscalergov1alpha1.Rules, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+	selector, err := workloadSelector(rule)
+	if err != nil {
+		return err
+	}
+
+	var cronJobs batchv1.CronJobList
+	if err := sc.client.Get(objectNamespace, &cronJobs); err != nil {
+		return err
+	}
+
+	for _, cronJob := range cronJobs.Items {
+		if excluded(selector, &cronJob) {
+			continue
+		}
+
+		currentSuspendValue := cronJobSuspendValue(&cronJob)
+
+		defaultScalingObjectValues := store.ScalingOperation{
+			RuleNameDescription: rule.Name,
+			DownscalerName:      store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
+			ResourceName:        cronJob.Name,
+			NamespaceName:       objectNamespace,
+			ResourceType:        types.CronJobObjectResource.String(),
+			Replicas:            cronJobActive,
+		}
+
+		if operationTypeReplicas == types.OperationDownscale {
+			defaultScalingObjectValues.Replicas = cronJobSuspended
+
+			if err := writeReplicas(
+				context.Background(),
+				sc.storeClient,
+				sc.persistence,
+				int32(currentSuspendValue),
+				currentSuspendValue == cronJobSuspended,
+				&defaultScalingObjectValues,
+			); err != nil {
+				if !errors.Is(err, ErrNotErrorDisabledPersitence) {
+					return err
+				}
+			}
+		}
+
+		recorded := false
+		if operationTypeReplicas == types.OperationUpscale {
+			err := readReplicas(
+				context.Background(),
+				sc.storeClient,
+				sc.persistence,
+				&defaultScalingObjectValues,
+			)
+			if err != nil && !errors.Is(err, ErrNotErrorDisabledPersitence) {
+				sc.logger.Error(err, "database", "reading suspend value error", err)
+				return err
+			}
+			recorded = err == nil
+		}
+
+		suspend := defaultScalingObjectValues.Replicas == cronJobSuspended
+		err := sc.client.PatchSuspend(suspend, &cronJob)
+		recordEvent(sc.storeClient, sc.persistence, sc.logger, operationTypeReplicas, defaultScalingObjectValues, currentSuspendValue, defaultScalingObjectValues.Replicas, err)
+		if err != nil {
+			sc.logger.Error(err, "client", "error patching cronjob", err)
+			return err
+		}
+		if recorded {
+			clearReplicas(context.Background(), sc.storeClient, sc.logger, &defaultScalingObjectValues)
+		}
+
+		sc.logger.Info("client",
+			"patching cronjob", cronJob.Name,
+			"namespace", objectNamespace,
+			"before", currentSuspendValue == cronJobSuspended,
+			"after", suspend,
+		)
+	}
+
+	return nil
+}
+
+func (sc *ScaleCronJob) Restore(scalingObject store.ScalingOperation) error {
+	var cronJob batchv1.CronJob
+	if err := sc.client.Get(scalingObject.NamespaceName, &cronJob, scalingObject.ResourceName); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	// only a cronjob suspended since it was recorded active is resumed.
+	if scalingObject.Replicas == cronJobSuspended || cronJobSuspendValue(&cronJob) == cronJobActive {
+		return nil
+	}
+
+	suspend := scalingObject.Replicas == cronJobSuspended
+	if err := sc.client.PatchSuspend(suspend, &cronJob); err != nil {
+		sc.logger.Error(err, "client", "error restoring cronjob", err)
+		return err
+	}
+
+	sc.logger.Info("client",
+		"restoring cronjob", cronJob.Name,
+		"namespace", scalingObject.NamespaceName,
+		"suspend", suspend,
+	)
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"sync"
 
 	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
 	"github.com/adalbertjnr/kubetime-scaler/internal/client"
@@ -11,16 +12,28 @@ import (
 	"github.com/adalbertjnr/kubetime-scaler/internal/types"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type ResourceScaler interface {
-	Run(downscalerObject downscalergov1alpha1.Downscaler, ruleName, namespace string, replicas types.ScalingOperation) error
+	// Run scales the objects of the namespace selected by the workloadSelector of the rule,
+	// skipping the ones annotated with ExcludeAnnotation.
+	Run(downscalerObject downscalergov1alpha1.Downscaler, rule downscalergov1alpha1.Rules, namespace string, replicas types.ScalingOperation) error
+	// Restore scales the workload recorded in scalingObject back to the recorded replicas, unless it
+	// already runs at least as many.
+	// A workload that no longer exists is not an error.
+	Restore(scalingObject store.ScalingOperation) error
 }
 
 type ScaleDeployment struct {
 	Client *client.APIClient
 	Logger logr.Logger
 
+	// selfNamespace holds the deployment sharing the name of the Downscaler object, keyed by
+	// the object namespace/name, so it is scaled after every other deployment in the namespace.
+	selfMu        sync.Mutex
 	selfNamespace map[string]downscalerDeploymentMetadata
 
 	persistence bool
@@ -33,6 +46,20 @@ var (
 	ErrNotErrorOperationDownscale = errors.New("downscale operation. no need to read the replicas in the database")
 )
 
+// ExcludeAnnotation set to "true" on any scaled object leaves it untouched by every rule.
+const ExcludeAnnotation = "kubetime-scaler/exclude"
+
+func workloadSelector(rule downscalergov1alpha1.Rules) (labels.Selector, error) {
+	if rule.WorkloadSelector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(rule.WorkloadSelector)
+}
+
+func excluded(selector labels.Selector, object metav1.Object) bool {
+	return object.GetAnnotations()[ExcludeAnnotation] == "true" || !selector.Matches(labels.Set(object.GetLabels()))
+}
+
 type downscalerDeploymentMetadata struct {
 	deployment             appsv1.Deployment
 	scalingOperationObject store.ScalingOperation
@@ -44,64 +71,131 @@ func readReplicas(ctx context.Context, sc *store.Persistence, persistence bool,
 	}
 
 	if err := sc.ScalingOperation.Get(ctx, defaultScalingObject); err != nil {
+		// the records of a volatile store are gone after a restart, the rule decides the replicas.
+		if sc.Volatile && errors.Is(err, sql.ErrNoRows) {
+			return ErrNotErrorDisabledPersitence
+		}
 		return err
 	}
 
 	return nil
 }
 
-func writeReplicas(ctx context.Context, sc *store.Persistence, persistence bool, currentObjectReplicas int32, defaultScalingObject *store.ScalingOperation) error {
+// writeReplicas records the replicas an upscale restores. An object found already scaled down keeps
+// the record of the downscale that scaled it, a downscale running again, such as on catch-up or
+// after a rule rename, would otherwise record the scaled down replicas over the original ones.
+func writeReplicas(ctx context.Context, sc *store.Persistence, persistence bool, currentObjectReplicas int32, scaledDown bool, defaultScalingObject *store.ScalingOperation) error {
 	if !persistence {
 		return ErrNotErrorDisabledPersitence
 	}
 
+	if scaledDown {
+		recorded := *defaultScalingObject
+		if err := sc.ScalingOperation.Get(ctx, &recorded); err == nil {
+			return nil
+		}
+	}
+
 	operationTypeReplicas := defaultScalingObject.Replicas
 	defaultScalingObject.Replicas = int(currentObjectReplicas)
 
-	if err := sc.ScalingOperation.Update(ctx, defaultScalingObject); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			if err := sc.ScalingOperation.Insert(ctx, defaultScalingObject); err != nil {
-				return err
-			}
-		}
+	if err := sc.ScalingOperation.Upsert(ctx, defaultScalingObject); err != nil {
+		return err
 	}
 
 	defaultScalingObject.Replicas = operationTypeReplicas
 	return nil
 }
 
-func (sc *ScaleDeployment) Run(downscalerObject downscalergov1alpha1.Downscaler, RuleNameDescription, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+// clearReplicas deletes the record an upscale restored, so the next downscale records the replicas of
+// its own cycle. Failing to delete it is logged, the next downscale overwrites the record of an object
+// it finds running.
+func clearReplicas(ctx context.Context, sc *store.Persistence, logger logr.Logger, scalingObject *store.ScalingOperation) {
+	if err := sc.ScalingOperation.Delete(ctx, scalingObject); err != nil {
+		logger.Error(err, "database", "deleting replicas error", err)
+	}
+}
+
+// recordEvent appends the outcome of a patch to the scaling history. Failing to record it is
+// logged and never fails the scaling itself.
+func recordEvent(sc *store.Persistence, persistence bool, logger logr.Logger, operation types.ScalingOperation, scalingObject store.ScalingOperation, before, after int, patchErr error) {
+	if !persistence {
+		return
+	}
+
+	event := store.NewScalingEvent(scalingObject, operation.String(), before, after, patchErr)
+	if err := sc.ScalingOperation.RecordEvent(context.Background(), event); err != nil {
+		logger.Error(err, "database", "recording scaling event error", err)
+	}
+}
+
+func (sc *ScaleDeployment) Run(downscalerObject downscalergov1alpha1.Downscaler, rule downscalergov1alpha1.Rules, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+	selector, err := workloadSelector(rule)
+	if err != nil {
+		return err
+	}
+
 	var deployments appsv1.DeploymentList
 	if err := sc.Client.Get(objectNamespace, &deployments); err != nil {
 		return err
 	}
 
+	// deployments managed by an hpa are never brought back below its minReplicas,
+	// otherwise the autoscaler would immediately scale them again.
+	autoscaledDeployments, err := hpaTargets(sc.Client, objectNamespace)
+	if err != nil {
+		sc.Logger.Info("client", "namespace", objectNamespace, "listing hpas error", err.Error())
+	}
+
+	selfKey := store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name)
+
 	defer func() {
-		if object, exists := sc.selfNamespace[downscalerObject.Name]; exists {
+		sc.selfMu.Lock()
+		object, exists := sc.selfNamespace[selfKey]
+		delete(sc.selfNamespace, selfKey)
+		sc.selfMu.Unlock()
+
+		if exists {
 			if err := sc.Client.Patch(object.scalingOperationObject.Replicas, &object.deployment); err != nil {
 				sc.Logger.Error(err, "client", "name", downscalerObject.Name, "self patching error", err)
 			}
-			delete(sc.selfNamespace, object.deployment.Name)
 		}
 	}()
 
 	for _, deployment := range deployments.Items {
+		if excluded(selector, &deployment) {
+			continue
+		}
+
 		currentObjectReplicas := *deployment.Spec.Replicas
 
+		target, explicit := replicaTarget(sc.Logger, rule, &deployment, operationTypeReplicas)
+		if operationTypeReplicas == types.OperationDownscale {
+			// a downscale keeping replicas stays at the minReplicas of the hpa, which would scale the
+			// deployment right back otherwise. At zero replicas the autoscaler is inactive.
+			if hpa, found := autoscaledDeployments[deployment.Name]; found && target > 0 && target < hpaMinReplicas(&hpa) {
+				target = hpaMinReplicas(&hpa)
+			}
+			target = downscaleTarget(target, currentObjectReplicas)
+		}
+
 		defaultScalingObjectValues := store.ScalingOperation{
 			ResourceName:        deployment.Name,
-			RuleNameDescription: RuleNameDescription,
+			RuleNameDescription: rule.Name,
+			DownscalerName:      store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
 			NamespaceName:       objectNamespace,
 			ResourceType:        types.DeploymentObjectResource.String(),
-			Replicas:            int(operationTypeReplicas),
+			Replicas:            target,
 		}
 
 		if operationTypeReplicas == types.OperationDownscale {
 			if deployment.Name == downscalerObject.Name {
-				sc.selfNamespace[downscalerObject.Name] = downscalerDeploymentMetadata{
+				sc.selfMu.Lock()
+				sc.selfNamespace[selfKey] = downscalerDeploymentMetadata{
 					deployment:             deployment,
 					scalingOperationObject: defaultScalingObjectValues,
 				}
+				sc.selfMu.Unlock()
 				continue
 			}
 
@@ -110,6 +204,7 @@ func (sc *ScaleDeployment) Run(downscalerObject downscalergov1alpha1.Downscaler,
 				sc.storeClient,
 				sc.persistence,
 				currentObjectReplicas,
+				int(currentObjectReplicas) <= target,
 				&defaultScalingObjectValues,
 			); err != nil {
 				if !errors.Is(err, ErrNotErrorDisabledPersitence) {
@@ -118,24 +213,34 @@ func (sc *ScaleDeployment) Run(downscalerObject downscalergov1alpha1.Downscaler,
 			}
 		}
 
+		recorded := false
 		if operationTypeReplicas == types.OperationUpscale {
-			if err := readReplicas(
+			err := readReplicas(
 				context.Background(),
 				sc.storeClient,
 				sc.persistence,
 				&defaultScalingObjectValues,
-			); err != nil {
-				if !errors.Is(err, ErrNotErrorDisabledPersitence) {
-					sc.Logger.Error(err, "database", "reading replicas error", err)
-					return err
-				}
+			)
+			if err != nil && !errors.Is(err, ErrNotErrorDisabledPersitence) && !(explicit && errors.Is(err, sql.ErrNoRows)) {
+				sc.Logger.Error(err, "database", "reading replicas error", err)
+				return err
+			}
+			recorded = err == nil
+
+			if hpa, found := autoscaledDeployments[deployment.Name]; found && defaultScalingObjectValues.Replicas < hpaMinReplicas(&hpa) {
+				defaultScalingObjectValues.Replicas = hpaMinReplicas(&hpa)
 			}
 		}
 
-		if err := sc.Client.Patch(defaultScalingObjectValues.Replicas, &deployment); err != nil {
+		err := sc.Client.Patch(defaultScalingObjectValues.Replicas, &deployment)
+		recordEvent(sc.storeClient, sc.persistence, sc.Logger, operationTypeReplicas, defaultScalingObjectValues, int(currentObjectReplicas), defaultScalingObjectValues.Replicas, err)
+		if err != nil {
 			sc.Logger.Error(err, "client", "error patching deployment", err)
 			return err
 		}
+		if recorded {
+			clearReplicas(context.Background(), sc.storeClient, sc.Logger, &defaultScalingObjectValues)
+		}
 
 		sc.Logger.Info("client",
 			"patching deployment", deployment.Name,
@@ -149,6 +254,33 @@ func (sc *ScaleDeployment) Run(downscalerObject downscalergov1alpha1.Downscaler,
 	return nil
 }
 
+func (sc *ScaleDeployment) Restore(scalingObject store.ScalingOperation) error {
+	var deployment appsv1.Deployment
+	if err := sc.Client.Get(scalingObject.NamespaceName, &deployment, scalingObject.ResourceName); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if deployment.Spec.Replicas != nil && int(*deployment.Spec.Replicas) >= scalingObject.Replicas {
+		return nil
+	}
+
+	if err := sc.Client.Patch(scalingObject.Replicas, &deployment); err != nil {
+		sc.Logger.Error(err, "client", "error restoring deployment", err)
+		return err
+	}
+
+	sc.Logger.Info("client",
+		"restoring deployment", deployment.Name,
+		"namespace", scalingObject.NamespaceName,
+		"after", scalingObject.Replicas,
+	)
+
+	return nil
+}
+
 type ScaleStatefulSet struct {
 	client *client.APIClient
 	logger logr.Logger
@@ -157,21 +289,36 @@ type ScaleStatefulSet struct {
 	storeClient *store.Persistence
 }
 
-func (sc *ScaleStatefulSet) Run(downscalerObject downscalergov1alpha1.Downscaler, ruleNameDescription, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+func (sc *ScaleStatefulSet) Run(downscalerObject downscalergov1alpha1.Downscaler, rule downscalergov1alpha1.Rules, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+	selector, err := workloadSelector(rule)
+	if err != nil {
+		return err
+	}
+
 	var statefulSets appsv1.StatefulSetList
 	if err := sc.client.Get(objectNamespace, &statefulSets); err != nil {
 		return err
 	}
 
 	for _, statefulSet := range statefulSets.Items {
+		if excluded(selector, &statefulSet) {
+			continue
+		}
+
 		currentObjectReplicas := *statefulSet.Spec.Replicas
 
+		target, explicit := replicaTarget(sc.logger, rule, &statefulSet, operationTypeReplicas)
+		if operationTypeReplicas == types.OperationDownscale {
+			target = downscaleTarget(target, currentObjectReplicas)
+		}
+
 		defaultScalingObjectValues := store.ScalingOperation{
-			RuleNameDescription: ruleNameDescription,
+			RuleNameDescription: rule.Name,
+			DownscalerName:      store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
 			ResourceName:        statefulSet.Name,
 			NamespaceName:       objectNamespace,
 			ResourceType:        types.StatefulSetObjectResource.String(),
-			Replicas:            int(operationTypeReplicas),
+			Replicas:            target,
 		}
 
 		if operationTypeReplicas == types.OperationDownscale {
@@ -179,6 +326,7 @@ func (sc *ScaleStatefulSet) Run(downscalerObject downscalergov1alpha1.Downscaler
 				sc.storeClient,
 				sc.persistence,
 				currentObjectReplicas,
+				int(currentObjectReplicas) <= target,
 				&defaultScalingObjectValues,
 			); err != nil {
 				if !errors.Is(err, ErrNotErrorDisabledPersitence) {
@@ -187,24 +335,30 @@ func (sc *ScaleStatefulSet) Run(downscalerObject downscalergov1alpha1.Downscaler
 			}
 		}
 
+		recorded := false
 		if operationTypeReplicas == types.OperationUpscale {
-			if err := readReplicas(
+			err := readReplicas(
 				context.Background(),
 				sc.storeClient,
 				sc.persistence,
 				&defaultScalingObjectValues,
-			); err != nil {
-				if !errors.Is(err, ErrNotErrorDisabledPersitence) {
-					sc.logger.Error(err, "database", "reading replicas error", err)
-					return err
-				}
+			)
+			if err != nil && !errors.Is(err, ErrNotErrorDisabledPersitence) && !(explicit && errors.Is(err, sql.ErrNoRows)) {
+				sc.logger.Error(err, "database", "reading replicas error", err)
+				return err
 			}
+			recorded = err == nil
 		}
 
-		if err := sc.client.Patch(defaultScalingObjectValues.Replicas, &statefulSet); err != nil {
+		err := sc.client.Patch(defaultScalingObjectValues.Replicas, &statefulSet)
+		recordEvent(sc.storeClient, sc.persistence, sc.logger, operationTypeReplicas, defaultScalingObjectValues, int(currentObjectReplicas), defaultScalingObjectValues.Replicas, err)
+		if err != nil {
 			sc.logger.Error(err, "client", "error patching deployment", err)
 			return err
 		}
+		if recorded {
+			clearReplicas(context.Background(), sc.storeClient, sc.logger, &defaultScalingObjectValues)
+		}
 
 		sc.logger.Info("client",
 			"patching statefulSet", statefulSet.Name,
@@ -218,6 +372,33 @@ func (sc *ScaleStatefulSet) Run(downscalerObject downscalergov1alpha1.Downscaler
 	return nil
 }
 
+func (sc *ScaleStatefulSet) Restore(scalingObject store.ScalingOperation) error {
+	var statefulSet appsv1.StatefulSet
+	if err := sc.client.Get(scalingObject.NamespaceName, &statefulSet, scalingObject.ResourceName); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if statefulSet.Spec.Replicas != nil && int(*statefulSet.Spec.Replicas) >= scalingObject.Replicas {
+		return nil
+	}
+
+	if err := sc.client.Patch(scalingObject.Replicas, &statefulSet); err != nil {
+		sc.logger.Error(err, "client", "error restoring statefulSet", err)
+		return err
+	}
+
+	sc.logger.Info("client",
+		"restoring statefulSet", statefulSet.Name,
+		"namespace", scalingObject.NamespaceName,
+		"after", scalingObject.Replicas,
+	)
+
+	return nil
+}
+
 type FactoryScaler map[types.ResourceType]ResourceScaler
 
 func NewScalerFactory(client *client.APIClient, store *store.Persistence, logger logr.Logger) *FactoryScaler {
@@ -237,5 +418,21 @@ func NewScalerFactory(client *client.APIClient, store *store.Persistence, logger
 			storeClient: store,
 			persistence: persistence,
 		},
+
+		// a pinned autoscaler whose bounds are lost on a restart would stay capped for good, so
+		// the volatile memory store never pins them.
+		types.HorizontalPodAutoscalerObjectResource: &ScaleHorizontalPodAutoscaler{
+			client:      client,
+			logger:      logger,
+			storeClient: store,
+			persistence: persistence && !store.Volatile,
+		},
+
+		types.CronJobObjectResource: &ScaleCronJob{
+			client:      client,
+			logger:      logger,
+			storeClient: store,
+			persistence: persistence,
+		},
 	}
 }
@@ -0,0 +1,178 @@
+package factory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/client"
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/go-logr/logr"
+	v2 "k8s.io/api/autoscaling/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// hpaPinnedReplicas is the value minReplicas and maxReplicas are pinned to while downscaled.
+// The API server does not accept a zero minReplicas without the HPAScaleToZero feature gate.
+const hpaPinnedReplicas = 1
+
+type ScaleHorizontalPodAutoscaler struct {
+	client *client.APIClient
+	logger logr.Logger
+
+	persistence bool
+	storeClient *store.Persistence
+}
+
+func hpaMinReplicas(hpa *v2.HorizontalPodAutoscaler) int {
+	if hpa.Spec.MinReplicas == nil {
+		return 1
+	}
+	return int(*hpa.Spec.MinReplicas)
+}
+
+// hpaTargets returns the autoscalers of the namespace keyed by the name of the deployment they scale.
+func hpaTargets(c *client.APIClient, namespace string) (map[string]v2.HorizontalPodAutoscaler, error) {
+	var hpas v2.HorizontalPodAutoscalerList
+	if err := c.Get(namespace, &hpas); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]v2.HorizontalPodAutoscaler)
+	for _, hpa := range hpas.Items {
+		if hpa.Spec.ScaleTargetRef.Kind == "Deployment" {
+			targets[hpa.Spec.ScaleTargetRef.Name] = hpa
+		}
+	}
+
+	return targets, nil
+}
+
+// Run pins minReplicas and maxReplicas on downscale and brings the saved values back on upscale.
+// Without a store keeping its records across restarts, the memory store included, the original
+// bounds could be lost while pinned, so nothing is done.
+func (sc *ScaleHorizontalPodAutoscaler) Run(downscalerObject downscalergov1alpha1.Downscaler, rule downscalergov1alpha1.Rules, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+	if !sc.persistence {
+		sc.logger.Info("client", "namespace", objectNamespace, "skipping hpa scaling", "a durable store is required to restore minReplicas and maxReplicas")
+		return nil
+	}
+
+	selector, err := workloadSelector(rule)
+	if err != nil {
+		return err
+	}
+
+	var hpas v2.HorizontalPodAutoscalerList
+	if err := sc.client.Get(objectNamespace, &hpas); err != nil {
+		return err
+	}
+
+	for _, hpa := range hpas.Items {
+		if excluded(selector, &hpa) {
+			continue
+		}
+
+		currentMinReplicas := hpaMinReplicas(&hpa)
+		currentMaxReplicas := int(hpa.Spec.MaxReplicas)
+
+		defaultScalingObjectValues := store.ScalingOperation{
+			RuleNameDescription: rule.Name,
+			DownscalerName:      store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
+			ResourceName:        hpa.Name,
+			NamespaceName:       objectNamespace,
+			ResourceType:        types.HorizontalPodAutoscalerObjectResource.String(),
+			Replicas:            currentMinReplicas,
+			MaxReplicas:         currentMaxReplicas,
+		}
+
+		// the autoscaler keeps the downscaleReplicas of the rule when it is above the pinned value.
+		target, _ := replicaTarget(sc.logger, rule, &hpa, types.OperationDownscale)
+		pinnedReplicas := max(hpaPinnedReplicas, target)
+		minReplicas, maxReplicas := pinnedReplicas, pinnedReplicas
+
+		if operationTypeReplicas == types.OperationDownscale {
+			if currentMinReplicas == pinnedReplicas && currentMaxReplicas == pinnedReplicas {
+				continue
+			}
+
+			if err := writeReplicas(
+				context.Background(),
+				sc.storeClient,
+				sc.persistence,
+				int32(currentMinReplicas),
+				// the pinned autoscalers were skipped above.
+				false,
+				&defaultScalingObjectValues,
+			); err != nil {
+				return err
+			}
+		}
+
+		if operationTypeReplicas == types.OperationUpscale {
+			if err := readReplicas(
+				context.Background(),
+				sc.storeClient,
+				sc.persistence,
+				&defaultScalingObjectValues,
+			); err != nil {
+				if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrNotErrorDisabledPersitence) {
+					sc.logger.Info("client", "hpa", hpa.Name, "namespace", objectNamespace, "skipping upscale", "no replicas were recorded")
+					continue
+				}
+				sc.logger.Error(err, "database", "reading replicas error", err)
+				return err
+			}
+
+			minReplicas, maxReplicas = defaultScalingObjectValues.Replicas, defaultScalingObjectValues.MaxReplicas
+		}
+
+		err := sc.client.PatchHPA(minReplicas, maxReplicas, &hpa)
+		recordEvent(sc.storeClient, sc.persistence, sc.logger, operationTypeReplicas, defaultScalingObjectValues, currentMinReplicas, minReplicas, err)
+		if err != nil {
+			sc.logger.Error(err, "client", "error patching hpa", err)
+			return err
+		}
+		if operationTypeReplicas == types.OperationUpscale {
+			clearReplicas(context.Background(), sc.storeClient, sc.logger, &defaultScalingObjectValues)
+		}
+
+		sc.logger.Info("client",
+			"patching hpa", hpa.Name,
+			"namespace", objectNamespace,
+			"before", []int{currentMinReplicas, currentMaxReplicas},
+			"after", []int{minReplicas, maxReplicas},
+		)
+	}
+
+	return nil
+}
+
+func (sc *ScaleHorizontalPodAutoscaler) Restore(scalingObject store.ScalingOperation) error {
+	var hpa v2.HorizontalPodAutoscaler
+	if err := sc.client.Get(scalingObject.NamespaceName, &hpa, scalingObject.ResourceName); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if scalingObject.MaxReplicas == 0 ||
+		(hpaMinReplicas(&hpa) >= scalingObject.Replicas && int(hpa.Spec.MaxReplicas) >= scalingObject.MaxReplicas) {
+		return nil
+	}
+
+	if err := sc.client.PatchHPA(scalingObject.Replicas, scalingObject.MaxReplicas, &hpa); err != nil {
+		sc.logger.Error(err, "client", "error restoring hpa", err)
+		return err
+	}
+
+	sc.logger.Info("client",
+		"restoring hpa", hpa.Name,
+		"namespace", scalingObject.NamespaceName,
+		"after", []int{scalingObject.Replicas, scalingObject.MaxReplicas},
+	)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package factory
+
+import (
+	"strconv"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DownscaleReplicasAnnotation and UpscaleReplicasAnnotation override, for a single object, the
+// downscaleReplicas and upscaleReplicas of the rule.
+const (
+	DownscaleReplicasAnnotation = "kubetime-scaler/downscale-replicas"
+	UpscaleReplicasAnnotation   = "kubetime-scaler/upscale-replicas"
+)
+
+const (
+	defaultDownscaleReplicas = 0
+	defaultUpscaleReplicas   = 1
+)
+
+// replicaTarget returns the replicas the object is scaled to by the operation, looking at the object
+// annotation first and then at the rule. explicit reports whether the value was configured instead of
+// being the default, which lets an upscale fall back to it when the store has no record of the object.
+func replicaTarget(logger logr.Logger, rule downscalergov1alpha1.Rules, object metav1.Object, operation types.ScalingOperation) (replicas int, explicit bool) {
+	annotation, ruleReplicas, fallback := DownscaleReplicasAnnotation, rule.DownscaleReplicas, defaultDownscaleReplicas
+	if operation == types.OperationUpscale {
+		annotation, ruleReplicas, fallback = UpscaleReplicasAnnotation, rule.UpscaleReplicas, defaultUpscaleReplicas
+	}
+
+	if value, found := object.GetAnnotations()[annotation]; found {
+		replicas, err := strconv.Atoi(value)
+		if err == nil && replicas >= 0 {
+			return replicas, true
+		}
+		logger.Info("client", "name", object.GetName(), "namespace", object.GetNamespace(), "ignoring invalid annotation", annotation, "value", value)
+	}
+
+	if ruleReplicas != nil {
+		return int(*ruleReplicas), true
+	}
+
+	return fallback, false
+}
+
+// downscaleTarget never lets a downscale add replicas to an object already running fewer than the target.
+func downscaleTarget(target int, currentObjectReplicas int32) int {
+	return min(target, int(currentObjectReplicas))
+}
@@ -0,0 +1,179 @@
+package factory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/client"
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ScaleSubresource scales any resource implementing the /scale subresource, so custom resources
+// declared in scalableResources need no dedicated scaler.
+type ScaleSubresource struct {
+	client *client.APIClient
+	logger logr.Logger
+
+	resourceType types.ResourceType
+	gvk          schema.GroupVersionKind
+
+	persistence bool
+	storeClient *store.Persistence
+}
+
+// NewScaleSubresourceScaler registers the kind of the resource in the store when it reads the scaled
+// objects itself, like the annotations store.
+func NewScaleSubresourceScaler(client *client.APIClient, storeClient *store.Persistence, logger logr.Logger, resource downscalergov1alpha1.ScalableResource) *ScaleSubresource {
+	gvk := schema.GroupVersionKind{
+		Group:   resource.Group,
+		Version: resource.Version,
+		Kind:    resource.Kind,
+	}
+
+	if storeClient != nil {
+		if registry, ok := storeClient.ScalingOperation.(store.KindRegistry); ok {
+			registry.RegisterKind(resource.Name, gvk)
+		}
+	}
+
+	return &ScaleSubresource{
+		client:       client,
+		logger:       logger,
+		resourceType: resource.Name,
+		gvk:          gvk,
+		persistence:  storeClient != nil,
+		storeClient:  storeClient,
+	}
+}
+
+func (sc *ScaleSubresource) Run(downscalerObject downscalergov1alpha1.Downscaler, rule downscalergov1alpha1.Rules, objectNamespace string, operationTypeReplicas types.ScalingOperation) error {
+	selector, err := workloadSelector(rule)
+	if err != nil {
+		return err
+	}
+
+	var objects unstructured.UnstructuredList
+	objects.SetGroupVersionKind(sc.gvk.GroupVersion().WithKind(sc.gvk.Kind + "List"))
+	if err := sc.client.Get(objectNamespace, &objects); err != nil {
+		return err
+	}
+
+	for _, object := range objects.Items {
+		if excluded(selector, &object) {
+			continue
+		}
+
+		object.SetGroupVersionKind(sc.gvk)
+
+		scale, err := sc.client.GetScale(&object)
+		if err != nil {
+			sc.logger.Error(err, "client", "kind", sc.gvk.Kind, "error reading scale", err)
+			return err
+		}
+		currentObjectReplicas := scale.Spec.Replicas
+
+		target, explicit := replicaTarget(sc.logger, rule, &object, operationTypeReplicas)
+		if operationTypeReplicas == types.OperationDownscale {
+			target = downscaleTarget(target, currentObjectReplicas)
+		}
+
+		defaultScalingObjectValues := store.ScalingOperation{
+			RuleNameDescription: rule.Name,
+			DownscalerName:      store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
+			ResourceName:        object.GetName(),
+			NamespaceName:       objectNamespace,
+			ResourceType:        sc.resourceType.String(),
+			Replicas:            target,
+		}
+
+		if operationTypeReplicas == types.OperationDownscale {
+			if err := writeReplicas(
+				context.Background(),
+				sc.storeClient,
+				sc.persistence,
+				currentObjectReplicas,
+				int(currentObjectReplicas) <= target,
+				&defaultScalingObjectValues,
+			); err != nil {
+				if !errors.Is(err, ErrNotErrorDisabledPersitence) {
+					return err
+				}
+			}
+		}
+
+		recorded := false
+		if operationTypeReplicas == types.OperationUpscale {
+			err := readReplicas(
+				context.Background(),
+				sc.storeClient,
+				sc.persistence,
+				&defaultScalingObjectValues,
+			)
+			if err != nil && !errors.Is(err, ErrNotErrorDisabledPersitence) && !(explicit && errors.Is(err, sql.ErrNoRows)) {
+				sc.logger.Error(err, "database", "reading replicas error", err)
+				return err
+			}
+			recorded = err == nil
+		}
+
+		err = sc.client.UpdateScale(defaultScalingObjectValues.Replicas, &object, scale)
+		recordEvent(sc.storeClient, sc.persistence, sc.logger, operationTypeReplicas, defaultScalingObjectValues, int(currentObjectReplicas), defaultScalingObjectValues.Replicas, err)
+		if err != nil {
+			sc.logger.Error(err, "client", "kind", sc.gvk.Kind, "error patching scale", err)
+			return err
+		}
+		if recorded {
+			clearReplicas(context.Background(), sc.storeClient, sc.logger, &defaultScalingObjectValues)
+		}
+
+		sc.logger.Info("client",
+			"patching "+sc.gvk.Kind, object.GetName(),
+			"namespace", objectNamespace,
+			"before", currentObjectReplicas,
+			"after", defaultScalingObjectValues.Replicas,
+			"object", defaultScalingObjectValues,
+		)
+	}
+
+	return nil
+}
+
+func (sc *ScaleSubresource) Restore(scalingObject store.ScalingOperation) error {
+	var object unstructured.Unstructured
+	object.SetGroupVersionKind(sc.gvk)
+	if err := sc.client.Get(scalingObject.NamespaceName, &object, scalingObject.ResourceName); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	scale, err := sc.client.GetScale(&object)
+	if err != nil {
+		return err
+	}
+
+	if int(scale.Spec.Replicas) >= scalingObject.Replicas {
+		return nil
+	}
+
+	if err := sc.client.UpdateScale(scalingObject.Replicas, &object, scale); err != nil {
+		sc.logger.Error(err, "client", "kind", sc.gvk.Kind, "error restoring scale", err)
+		return err
+	}
+
+	sc.logger.Info("client",
+		"restoring "+sc.gvk.Kind, object.GetName(),
+		"namespace", scalingObject.NamespaceName,
+		"after", scalingObject.Replicas,
+	)
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/calendar"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	reasonCalendarsRead   = "CalendarsRead"
+	reasonCalendarInvalid = "CalendarInvalid"
+)
+
+// calendarDayStart is the expression of the entry applying the forced days of the calendars, at
+// midnight in the time zone of the rule.
+const calendarDayStart = "0 0 0 * * *"
+
+// calendars returns the calendars applying to the rule.
+func (dc *Downscaler) calendars(rule downscalergov1alpha1.Rules) []downscalergov1alpha1.Calendar {
+	var calendars []downscalergov1alpha1.Calendar
+	for _, c := range dc.app.Spec.Calendars {
+		if len(c.Rules) == 0 || slices.Contains(c.Rules, rule.Name) {
+			calendars = append(calendars, c)
+		}
+	}
+	return calendars
+}
+
+// calendarException returns the first exception of the calendars of the rule covering at.
+func (dc *Downscaler) calendarException(rule downscalergov1alpha1.Rules, at time.Time) (calendar.Exception, bool) {
+	location := dc.ruleLocation(rule)
+
+	for _, c := range dc.calendars(rule) {
+		exceptions, err := dc.readCalendar(c, location)
+		dc.reportCalendar(c.Name, err)
+
+		if exception, found := calendar.Find(exceptions, at.In(location)); found {
+			return exception, true
+		}
+	}
+
+	return calendar.Exception{}, false
+}
+
+// readCalendar returns the exceptions of the calendar. The events of its ics file that can't be
+// read, or the whole file, are logged and left out, so they do not stop the schedule, and returned
+// in the error.
+func (dc *Downscaler) readCalendar(c downscalergov1alpha1.Calendar, location *time.Location) ([]calendar.Exception, error) {
+	exceptions, err := calendar.FromSpec(c.Exceptions, location)
+	if err != nil {
+		dc.log.Error(err, "calendar", "calendar", c.Name, "reading exceptions error", err)
+		return nil, err
+	}
+	if c.ICS == nil {
+		return exceptions, nil
+	}
+
+	events, err := dc.icsEvents(c.ICS, location)
+	if err != nil {
+		dc.log.Error(err, "calendar", "calendar", c.Name, "reading ics error", err)
+	}
+	for _, event := range events {
+		exceptions = append(exceptions, calendar.Exception{Event: event, Action: c.ICS.Action})
+	}
+	return exceptions, err
+}
+
+// checkCalendars reads every calendar, so the CalendarsValid condition is reported before a job
+// reads them.
+func (dc *Downscaler) checkCalendars() {
+	if len(dc.app.Spec.Calendars) == 0 {
+		dc.mu.Lock()
+		meta.RemoveStatusCondition(&dc.status.Conditions, downscalergov1alpha1.ConditionCalendarsValid)
+		dc.mu.Unlock()
+		return
+	}
+
+	location := dc.ruleLocation(downscalergov1alpha1.Rules{})
+	for _, c := range dc.app.Spec.Calendars {
+		_, err := dc.readCalendar(c, location)
+		dc.reportCalendar(c.Name, err)
+	}
+}
+
+// reportCalendar records the result of the last read of the calendar and sets the CalendarsValid
+// condition from the last read of every calendar.
+func (dc *Downscaler) reportCalendar(name string, err error) {
+	dc.mu.Lock()
+	if dc.calendarErrors == nil {
+		dc.calendarErrors = make(map[string]error)
+	}
+	if err != nil {
+		dc.calendarErrors[name] = err
+	} else {
+		delete(dc.calendarErrors, name)
+	}
+
+	var calendarErrors []error
+	for _, c := range dc.app.Spec.Calendars {
+		if err, found := dc.calendarErrors[c.Name]; found {
+			calendarErrors = append(calendarErrors, fmt.Errorf("calendar %s: %v", c.Name, err))
+		}
+	}
+	dc.mu.Unlock()
+
+	if len(calendarErrors) > 0 {
+		dc.setCondition(downscalergov1alpha1.ConditionCalendarsValid, metav1.ConditionFalse, reasonCalendarInvalid, errors.Join(calendarErrors...).Error())
+		return
+	}
+	dc.setCondition(downscalergov1alpha1.ConditionCalendarsValid, metav1.ConditionTrue, reasonCalendarsRead, "all calendars were read")
+}
+
+// icsEvents reads the ics file of the calendar through the api reader, so the ConfigMap is read
+// again on every job without being cached. A ConfigMap of another namespace is never read, the
+// webhook rejects it but objects admitted before may still point at one.
+func (dc *Downscaler) icsEvents(source *downscalergov1alpha1.ICSSource, location *time.Location) ([]calendar.Event, error) {
+	namespace := dc.app.Namespace
+	if source.ConfigMapRef.Namespace != "" && source.ConfigMapRef.Namespace != namespace {
+		return nil, fmt.Errorf("configmap %s/%s is not in the namespace of the downscaler", source.ConfigMapRef.Namespace, source.ConfigMapRef.Name)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	key := k8stypes.NamespacedName{Name: source.ConfigMapRef.Name, Namespace: namespace}
+	if err := dc.apiReader.Get(context.Background(), key, configMap); err != nil {
+		return nil, err
+	}
+
+	content, found := configMap.Data[source.ConfigMapRef.Key]
+	if !found {
+		return nil, fmt.Errorf("configmap %s/%s has no key %s", namespace, source.ConfigMapRef.Name, source.ConfigMapRef.Key)
+	}
+
+	return calendar.ParseICS(content, location)
+}
+
+// allowedByCalendar reports whether the operation of a job runs for the namespace of the rule now,
+// logging the exception that skips it.
+func (dc *Downscaler) allowedByCalendar(rule downscalergov1alpha1.Rules, namespace string, operation types.ScalingOperation) bool {
+	exception, found := dc.calendarException(rule, time.Now())
+	if !found {
+		return true
+	}
+	if calendar.Allows(exception.Action, operation) && !dc.inForcedPhase(rule.Name, namespace, exception.Action) {
+		return true
+	}
+
+	dc.log.Info("calendar",
+		"rule", rule.Name,
+		"namespace", namespace,
+		"operation", operation.String(),
+		"action", exception.Action,
+		"exception", exception.Summary,
+		"skipping the job", true,
+	)
+	return false
+}
+
+// inForcedPhase reports whether the namespace is already in the phase a forced day keeps it in.
+// It is not scaled again that day, a second downscale would record the replicas left by the first
+// one as the ones to restore.
+func (dc *Downscaler) inForcedPhase(ruleName, namespace string, action downscalergov1alpha1.CalendarAction) bool {
+	var phase downscalergov1alpha1.ScalingPhase
+	switch action {
+	case downscalergov1alpha1.CalendarActionForceDown:
+		phase = downscalergov1alpha1.PhaseDown
+	case downscalergov1alpha1.CalendarActionForceUp:
+		phase = downscalergov1alpha1.PhaseUp
+	default:
+		return false
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	s := dc.namespaceStatus(ruleName, namespace)
+	return s != nil && s.Phase == phase
+}
+
+// calendarJob scales the namespaces of the rule at the start of a forced day, so they stay down, or
+// up, all day whatever their state was. The namespaces already in the forced phase are left as they are.
+func (dc *Downscaler) calendarJob(ruleIndex int) func() {
+	return func() {
+		rule := dc.rules()[ruleIndex]
+
+		exception, found := dc.calendarException(rule, time.Now())
+		if !found {
+			return
+		}
+
+		var operation types.ScalingOperation
+		switch exception.Action {
+		case downscalergov1alpha1.CalendarActionForceDown:
+			operation = types.OperationDownscale
+		case downscalergov1alpha1.CalendarActionForceUp:
+			operation = types.OperationUpscale
+		default:
+			return
+		}
+
+		dc.log.Info("calendar", "rule", rule.Name, "action", exception.Action, "exception", exception.Summary)
+
+		namespaces := make([]string, 0, len(rule.Namespaces))
+		for _, namespace := range rule.Namespaces {
+			namespaces = append(namespaces, namespace.String())
+		}
+		if rule.NamespaceSelector != nil {
+			selected, err := dc.selectNamespaces(rule)
+			if err != nil {
+				dc.log.Error(err, "calendar", "rule", rule.Name, "selecting namespaces error", err)
+			}
+			namespaces = append(namespaces, selected...)
+		}
+
+		for _, namespace := range namespaces {
+			if dc.inForcedPhase(rule.Name, namespace, exception.Action) {
+				continue
+			}
+			dc.scaleNamespace(rule, namespace, operation)
+		}
+
+		dc.refreshNextRuns()
+		dc.publishStatus()
+	}
+}
+
+// addCalendarJob schedules calendarJob for a rule some calendar applies to.
+func (dc *Downscaler) addCalendarJob(ruleIndex int, rule downscalergov1alpha1.Rules) error {
+	if len(dc.calendars(rule)) == 0 {
+		return nil
+	}
+
+	expression := calendarDayStart
+	if rule.TimeZone != "" {
+		expression = "CRON_TZ=" + rule.TimeZone + " " + expression
+	}
+
+	entryID, err := dc.cron.AddFunc(expression, dc.calendarJob(ruleIndex))
+	if err != nil {
+		return fmt.Errorf("rule %q calendar: %v", rule.Name, err)
+	}
+
+	dc.log.Info("cron", "rule_description", rule.Name, "expression", expression, "assigning calendar entryID", entryID)
+	return nil
+}
+
+// ruleLocation returns the time zone the rule is scheduled in.
+func (dc *Downscaler) ruleLocation(rule downscalergov1alpha1.Rules) *time.Location {
+	for _, name := range []string{rule.TimeZone, dc.app.Spec.Schedule.TimeZone} {
+		if name == "" {
+			continue
+		}
+		if location, err := time.LoadLocation(name); err == nil {
+			return location
+		}
+	}
+	return time.Local
+}
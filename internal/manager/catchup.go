@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
+)
+
+// runCatchUp catches up the namespaces away from the Scheduler lock held by Run, scaling them can
+// take as long as the jobs. ctx is canceled when the Downscaler is replaced, the new one catches up
+// the namespaces left.
+func (dc *Downscaler) runCatchUp(ctx context.Context) {
+	dc.catchUp(ctx, time.Now())
+
+	dc.refreshNextRuns()
+	dc.publishStatus()
+}
+
+// catchUp scales the namespaces of every rule to the state expected at now, so a restart or an
+// edit made after a scheduled time does not wait for the next activation. Only a namespace whose
+// reported phase differs is scaled: up namespaces, or never scaled ones, inside a downscaled window
+// are scaled down and namespaces reported down outside of it are scaled up. A namespace reported
+// failed is caught up either way, like a missed run. A namespace that never went through the
+// controller is not scaled up, as there are no replicas to restore.
+func (dc *Downscaler) catchUp(ctx context.Context, now time.Time) {
+	for _, rule := range dc.rules() {
+		if !rule.CatchUpEnabled() {
+			continue
+		}
+
+		operation, found := dc.expectedOperation(rule, now)
+		if !found {
+			continue
+		}
+
+		namespaces := make([]string, 0, len(rule.Namespaces))
+		for _, namespace := range rule.Namespaces {
+			namespaces = append(namespaces, namespace.String())
+		}
+		if rule.NamespaceSelector != nil {
+			selected, err := dc.selectNamespaces(rule)
+			if err != nil {
+				dc.log.Error(err, "catch-up", "rule", rule.Name, "selecting namespaces error", err)
+			}
+			namespaces = append(namespaces, selected...)
+		}
+
+		for _, namespace := range namespaces {
+			if ctx.Err() != nil {
+				return
+			}
+			if !dc.needsCatchUp(rule.Name, namespace, operation) {
+				continue
+			}
+
+			dc.log.Info("catch-up", "rule", rule.Name, "namespace", namespace, "operation", operation.String())
+			dc.scaleNamespace(rule, namespace, operation)
+		}
+	}
+}
+
+// expectedOperation returns the last operation that ran for the rule at now, among the downscales
+// and upscales of all its windows, unless a calendar forces the day. found is false when the rule
+// never ran or is skipped by its calendar.
+func (dc *Downscaler) expectedOperation(rule downscalergov1alpha1.Rules, now time.Time) (types.ScalingOperation, bool) {
+	if exception, found := dc.calendarException(rule, now); found {
+		switch exception.Action {
+		case downscalergov1alpha1.CalendarActionForceDown:
+			return types.OperationDownscale, true
+		case downscalergov1alpha1.CalendarActionForceUp:
+			return types.OperationUpscale, true
+		default:
+			return types.OperationDownscale, false
+		}
+	}
+
+	location := dc.ruleLocation(rule)
+
+	var (
+		last      time.Time
+		operation types.ScalingOperation
+	)
+	for _, window := range rule.ScheduleWindows() {
+		for _, o := range []types.ScalingOperation{types.OperationDownscale, types.OperationUpscale} {
+			// invalid expressions are reported by initializeCronTasks.
+			expression, err := dc.buildCronExpression(rule, window, o)
+			if err != nil {
+				continue
+			}
+			schedule, err := utils.CronParser.Parse(expression)
+			if err != nil {
+				continue
+			}
+
+			if previous, found := utils.PreviousActivation(schedule, now.In(location)); found && previous.After(last) {
+				last, operation = previous, o
+			}
+		}
+	}
+
+	return operation, !last.IsZero()
+}
+
+func (dc *Downscaler) needsCatchUp(ruleName, namespace string, operation types.ScalingOperation) bool {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	var phase downscalergov1alpha1.ScalingPhase
+	if s := dc.namespaceStatus(ruleName, namespace); s != nil {
+		phase = s.Phase
+	}
+
+	if phase == downscalergov1alpha1.PhaseFailed {
+		return true
+	}
+	if operation == types.OperationDownscale {
+		return phase == "" || phase == downscalergov1alpha1.PhaseUp
+	}
+	return phase == downscalergov1alpha1.PhaseDown
+}
@@ -0,0 +1,178 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	reasonDatabaseAvailable   = "DatabaseAvailable"
+	reasonDatabaseUnavailable = "DatabaseUnavailable"
+
+	databasePingTimeout = 5 * time.Second
+)
+
+// databaseAvailable exposes the result of the last database check on the metrics endpoint. The
+// database is not a readiness check: the webhook served by the same pod would reject every
+// Downscaler while it is down.
+var databaseAvailable = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "kubetime_scaler_database_available",
+	Help: "Whether the database of the store answered the last check, 1 when it did.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(databaseAvailable)
+}
+
+// databaseRetryInterval is how often the queued jobs check whether the database is back.
+var databaseRetryInterval = 30 * time.Second
+
+// queuedJob identifies the namespace of a rule waiting for the database. Only the last operation
+// is kept, an upscale queued after a downscale replaces it.
+type queuedJob struct {
+	ruleName  string
+	namespace string
+}
+
+// checkDatabase pings the database of the store, if any, migrates its schema when that did not
+// succeed yet and reports the result in the DatabaseAvailable condition. Stores without a database
+// are always available.
+func (dc *Downscaler) checkDatabase(ctx context.Context) error {
+	if !dc.persistence {
+		return nil
+	}
+	_, healthChecker := dc.store.ScalingOperation.(store.HealthChecker)
+
+	if healthChecker {
+		pingCtx, cancel := context.WithTimeout(ctx, databasePingTimeout)
+		defer cancel()
+
+		if err := dc.store.Ping(pingCtx); err != nil {
+			dc.log.Error(err, "database", "downscaler", dc.app.Name, "database is unavailable", err)
+			dc.setCondition(downscalergov1alpha1.ConditionDatabaseAvailable, metav1.ConditionFalse, reasonDatabaseUnavailable, err.Error())
+			databaseAvailable.Set(0)
+			return err
+		}
+	}
+
+	if err := dc.handleDatabase(); err != nil {
+		dc.setCondition(downscalergov1alpha1.ConditionDatabaseAvailable, metav1.ConditionFalse, reasonDatabaseUnavailable, err.Error())
+		databaseAvailable.Set(0)
+		return err
+	}
+
+	if healthChecker {
+		dc.setCondition(downscalergov1alpha1.ConditionDatabaseAvailable, metav1.ConditionTrue, reasonDatabaseAvailable, "database is reachable")
+	}
+	databaseAvailable.Set(1)
+	return nil
+}
+
+// queueUntilDatabaseAvailable skips a job while the database is down, the replicas could be
+// neither saved on downscale nor read back on upscale. The job runs once the database answers.
+func (dc *Downscaler) queueUntilDatabaseAvailable(ruleName, namespace string, operation types.ScalingOperation, err error) {
+	job := queuedJob{ruleName: ruleName, namespace: namespace}
+
+	dc.mu.Lock()
+	if dc.queuedJobs == nil {
+		dc.queuedJobs = make(map[queuedJob]types.ScalingOperation)
+	}
+	_, alreadyQueued := dc.queuedJobs[job]
+	dc.queuedJobs[job] = operation
+
+	if s := dc.ensureNamespaceStatus(ruleName, namespace); s != nil {
+		s.Message = fmt.Sprintf("%s queued until the database is available: %v", operation, err)
+	}
+	ctx := dc.jobsCtx
+	dc.mu.Unlock()
+
+	dc.log.Info("database", "rule", ruleName, "namespace", namespace, "operation", operation.String(), "queued until the database is available", err.Error())
+
+	if !alreadyQueued && ctx != nil {
+		go dc.runWhenDatabaseAvailable(ctx, job)
+	}
+}
+
+// dequeue drops the queued operation of the namespace, a job running on time supersedes it.
+func (dc *Downscaler) dequeue(ruleName, namespace string) (types.ScalingOperation, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	job := queuedJob{ruleName: ruleName, namespace: namespace}
+	operation, found := dc.queuedJobs[job]
+	delete(dc.queuedJobs, job)
+
+	return operation, found
+}
+
+func (dc *Downscaler) runWhenDatabaseAvailable(ctx context.Context, job queuedJob) {
+	ticker := time.NewTicker(databaseRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := dc.checkDatabase(ctx); err != nil {
+			dc.publishStatus()
+			continue
+		}
+
+		operation, found := dc.dequeue(job.ruleName, job.namespace)
+		if !found {
+			return
+		}
+
+		for _, rule := range dc.rules() {
+			if rule.Name == job.ruleName {
+				dc.log.Info("database", "rule", job.ruleName, "namespace", job.namespace, "running queued", operation.String())
+				dc.scaleNamespace(rule, job.namespace, operation)
+			}
+		}
+
+		dc.refreshNextRuns()
+		dc.publishStatus()
+		return
+	}
+}
+
+// takeQueuedJobs empties the queue of a Downscaler being replaced, so the Scheduler hands the
+// jobs over to the new one.
+func (dc *Downscaler) takeQueuedJobs() map[queuedJob]types.ScalingOperation {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	queued := dc.queuedJobs
+	dc.queuedJobs = nil
+	return queued
+}
+
+// jobsContext returns the context of the cron set, canceled once the Downscaler is replaced.
+func (dc *Downscaler) jobsContext() context.Context {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	return dc.jobsCtx
+}
+
+// resumeQueuedJobs restarts the wait of the jobs handed over from the Downscaler this one replaced.
+func (dc *Downscaler) resumeQueuedJobs(ctx context.Context) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.jobsCtx = ctx
+	for job := range dc.queuedJobs {
+		go dc.runWhenDatabaseAvailable(ctx, job)
+	}
+}
@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// Finalize stops the cron jobs of the object, scales every workload it recorded back to the
+// recorded replicas when it runs fewer and removes all of its records, whatever the phase of
+// the namespaces, so a later object with the same name does not restore stale replicas.
+func (s *Scheduler) Finalize(ctx context.Context, app downscalergov1alpha1.Downscaler) error {
+	s.Remove(k8stypes.NamespacedName{Name: app.Name, Namespace: app.Namespace})
+
+	return s.newDownscaler().Add(ctx, app).restore(ctx)
+}
+
+func (dc *Downscaler) restore(ctx context.Context) error {
+	if !dc.persistence {
+		dc.log.Info("finalizer", "downscaler", dc.app.Name, "persistence is disabled", "no replicas were recorded to be restored")
+		return nil
+	}
+
+	// the scalers of the custom resources register their kind in the stores listing the objects.
+	for _, scalableResource := range dc.app.Spec.DownscalerOptions.ScalableResources {
+		dc.resourceScaler(scalableResource.Name)
+	}
+
+	scalingObjects, err := dc.store.ScalingOperation.List(ctx, store.ScalingOperationFilter{
+		DownscalerName: store.DownscalerKey(dc.app.Namespace, dc.app.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("listing replicas: %v", err)
+	}
+
+	var restoreErrors []error
+	for _, scalingObject := range scalingObjects {
+		resourceScaler, created := dc.resourceScaler(types.ResourceType(scalingObject.ResourceType))
+		if !created {
+			dc.log.Info("finalizer", "downscaler", dc.app.Name, "resource type", scalingObject.ResourceType, "no scaler", "dropping the record without restoring it")
+		} else if err := resourceScaler.Restore(scalingObject); err != nil {
+			restoreErrors = append(restoreErrors, fmt.Errorf("%s %s/%s: %v", scalingObject.ResourceType, scalingObject.NamespaceName, scalingObject.ResourceName, err))
+			continue
+		}
+
+		if err := dc.store.ScalingOperation.Delete(ctx, &scalingObject); err != nil {
+			restoreErrors = append(restoreErrors, fmt.Errorf("deleting replicas of %s/%s: %v", scalingObject.NamespaceName, scalingObject.ResourceName, err))
+		}
+	}
+
+	dc.log.Info("finalizer", "downscaler", dc.app.Name, "restored objects", len(scalingObjects))
+
+	return errors.Join(restoreErrors...)
+}
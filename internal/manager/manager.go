@@ -2,8 +2,9 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
@@ -11,14 +12,18 @@ import (
 	"github.com/adalbertjnr/kubetime-scaler/internal/factory"
 	"github.com/adalbertjnr/kubetime-scaler/internal/store"
 	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
 	"github.com/go-logr/logr"
 	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Downscaler struct {
 	app                downscalergov1alpha1.Downscaler
 	client             *client.APIClient
+	apiReader          ctrlclient.Reader
 	cron               *cron.Cron
 	log                logr.Logger
 	getFactory         *factory.FactoryScaler
@@ -26,6 +31,18 @@ type Downscaler struct {
 	store              *store.Persistence
 	persistence        bool
 	cancelFunc         context.CancelFunc
+
+	// mu guards the status and the cron pointer, both are touched by the cron jobs
+	mu     sync.Mutex
+	status downscalergov1alpha1.DownscalerStatus
+	// queuedJobs holds the jobs skipped while the database was down, jobsCtx stops their wait on
+	// reconcile, where the Scheduler hands them over to the Downscaler replacing this one.
+	queuedJobs map[queuedJob]types.ScalingOperation
+	jobsCtx    context.Context
+	// bootstrapped is set once the schema migration succeeded.
+	bootstrapped bool
+	// calendarErrors holds the error of the last read of each calendar that failed.
+	calendarErrors map[string]error
 }
 
 func (dc *Downscaler) Client(c *client.APIClient) *Downscaler {
@@ -33,6 +50,13 @@ func (dc *Downscaler) Client(c *client.APIClient) *Downscaler {
 	return dc
 }
 
+// APIReader sets the uncached reader the calendars read their ics ConfigMaps with, the cache of
+// the manager does not watch ConfigMaps.
+func (dc *Downscaler) APIReader(r ctrlclient.Reader) *Downscaler {
+	dc.apiReader = r
+	return dc
+}
+
 func (dc *Downscaler) Factory(f *factory.FactoryScaler) *Downscaler {
 	dc.getFactory = f
 	return dc
@@ -51,14 +75,30 @@ func (dc *Downscaler) Logger(l logr.Logger) *Downscaler {
 	return dc
 }
 
-func (dc *Downscaler) handleDatabase() {
+// handleDatabase migrates the database schema once. A failure, such as a database down at startup,
+// does not stop the scheduling: checkDatabase retries the migration and the jobs are queued until
+// it succeeds, as the replicas recorded on downscale could not be read back on upscale otherwise.
+func (dc *Downscaler) handleDatabase() error {
 	if !dc.persistence {
-		return
+		return nil
+	}
+
+	dc.mu.Lock()
+	bootstrapped := dc.bootstrapped
+	dc.mu.Unlock()
+	if bootstrapped {
+		return nil
 	}
+
 	if err := dc.store.ScalingOperation.Bootstrap(context.Background()); err != nil {
-		dc.log.Error(err, "database", "table bootstrap error", err)
-		return
+		dc.log.Error(err, "database", "schema migration error", err)
+		return fmt.Errorf("database migrations failed: %v", err)
 	}
+
+	dc.mu.Lock()
+	dc.bootstrapped = true
+	dc.mu.Unlock()
+	return nil
 }
 
 func (dc *Downscaler) Run() (ctrl.Result, error) {
@@ -66,66 +106,110 @@ func (dc *Downscaler) Run() (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	dc.handleDatabase()
+	bootstrapErr := dc.handleDatabase()
 
 	dc.initializeCronTasks()
+	go dc.runCatchUp(dc.jobsContext())
+
+	if bootstrapErr != nil {
+		// reports the DatabaseAvailable condition, retrying the migration once more.
+		_ = dc.checkDatabase(context.Background())
+		dc.publishStatus()
+	}
 
 	return ctrl.Result{}, nil
 }
 
-func (dc *Downscaler) addCronJob(ruleNameDescription, scaleStr string, overrideScaling []types.ResourceType, namespace downscalergov1alpha1.Namespace, defaultScaleReplicas types.ScalingOperation) {
-	expression := dc.buildCronExpression(dc.recurrence(), scaleStr)
+func (dc *Downscaler) addCronJob(entry cronEntries, rule downscalergov1alpha1.Rules, window downscalergov1alpha1.Window, cmd func()) error {
+	expression, err := dc.buildCronExpression(rule, window, entry.operation)
+	if err != nil {
+		dc.log.Error(err, "cron", "invalid schedule", err)
+		return fmt.Errorf("rule %q namespace %s: %v", entry.ruleNameDescription, entry.target(), err)
+	}
+	// the scheduler runs in the time zone of the schedule, a rule declaring its own gets it per entry.
+	if rule.TimeZone != "" {
+		expression = "CRON_TZ=" + rule.TimeZone + " " + expression
+	}
 
-	entryID, err := dc.cron.AddFunc(expression, dc.job(namespace, defaultScaleReplicas))
+	entryID, err := dc.cron.AddFunc(expression, cmd)
 	if err != nil {
 		dc.log.Error(err, "cron", "scheduling error", err)
-		return
+		return fmt.Errorf("rule %q namespace %s: %v", entry.ruleNameDescription, entry.target(), err)
 	}
 
-	dc.cronEntriesMapping[entryID] = cronEntries{
-		ruleNameDescription: ruleNameDescription,
-		namespace:           namespace.String(),
-		overrideReplicas:    overrideScaling,
-	}
+	dc.cronEntriesMapping[entryID] = entry
 
 	dc.log.Info("cron",
-		"namespace", namespace,
-		"override_scaling", overrideScaling,
+		"namespace", entry.target(),
+		"override_scaling", entry.overrideReplicas,
+		"expression", expression,
 		"assigning cron entryID", entryID,
-		"rule_description", ruleNameDescription,
+		"rule_description", entry.ruleNameDescription,
 	)
+
+	return nil
 }
 
 func (dc *Downscaler) job(namespace downscalergov1alpha1.Namespace, defaultScaleReplicas types.ScalingOperation) func() {
 	return func() {
 		for _, rule := range dc.rules() {
-			if namespace.Found(rule.Namespaces) {
-
-				overrideResource := rule.OverrideScaling
-				if len(overrideResource) == 0 {
-					overrideResource = dc.resourceScaling()
-				}
-
-				dc.execute(rule.Name, namespace.String(), defaultScaleReplicas, overrideResource)
+			if namespace.Found(rule.Namespaces) && dc.allowedByCalendar(rule, namespace.String(), defaultScaleReplicas) {
+				dc.scaleNamespace(rule, namespace.String(), defaultScaleReplicas)
 			}
 		}
+
+		dc.refreshNextRuns()
+		dc.publishStatus()
 	}
 }
 
-func (dc *Downscaler) execute(ruleName, namespace string, replicas types.ScalingOperation, overrideResource []types.ResourceType) {
+func (dc *Downscaler) scaleNamespace(rule downscalergov1alpha1.Rules, namespace string, defaultScaleReplicas types.ScalingOperation) {
+	overrideResource := rule.OverrideScaling
+	if len(overrideResource) == 0 {
+		overrideResource = dc.resourceScaling()
+	}
+
+	if err := dc.checkDatabase(context.Background()); err != nil {
+		dc.queueUntilDatabaseAvailable(rule.Name, namespace, defaultScaleReplicas, err)
+		dc.publishStatus()
+		return
+	}
+	dc.dequeue(rule.Name, namespace)
+
+	dc.recordTransition(rule.Name, namespace)
+	dc.publishStatus()
+
+	err := dc.execute(rule, namespace, defaultScaleReplicas, overrideResource)
+	dc.recordResult(rule.Name, namespace, defaultScaleReplicas, err)
+}
+
+func (dc *Downscaler) execute(rule downscalergov1alpha1.Rules, namespace string, replicas types.ScalingOperation, overrideResource []types.ResourceType) error {
+	var scalingErrors []error
 	for _, resource := range overrideResource {
-		if resourceScaler, created := (*dc.getFactory)[resource]; created {
-			if err := resourceScaler.Run(dc.app, ruleName, namespace, replicas); err != nil {
+		if resourceScaler, created := dc.resourceScaler(resource); created {
+			if err := resourceScaler.Run(dc.app, rule, namespace, replicas); err != nil {
 				dc.log.Error(err, "job", "resource", resource, "scaling error", err)
+				scalingErrors = append(scalingErrors, fmt.Errorf("%s: %v", resource, err))
 			}
 		}
 	}
+	return errors.Join(scalingErrors...)
 }
 
 type cronEntries struct {
 	ruleNameDescription string
 	namespace           string
+	namespaceSelector   string
 	overrideReplicas    []types.ResourceType
+	operation           types.ScalingOperation
+}
+
+// target returns the namespace of the entry or, for entries resolved at execution time, its selector.
+func (e cronEntries) target() string {
+	if e.namespaceSelector != "" {
+		return "selector(" + e.namespaceSelector + ")"
+	}
+	return e.namespace
 }
 
 func (dc *Downscaler) initializeCronTasks() {
@@ -137,31 +221,68 @@ func (dc *Downscaler) initializeCronTasks() {
 		}
 	}
 
-	for _, rule := range dc.rules() {
-		for _, namespace := range rule.Namespaces {
-			dc.addCronJob(rule.Name, rule.UpscaleTime, rule.OverrideScaling, namespace, types.OperationUpscale)
-			dc.addCronJob(rule.Name, rule.DownscaleTime, rule.OverrideScaling, namespace, types.OperationDownscale)
+	dc.initializeStatus()
+
+	var scheduleErrors []error
+	for index, rule := range dc.rules() {
+		if err := dc.addCalendarJob(index, rule); err != nil {
+			scheduleErrors = append(scheduleErrors, err)
+		}
+
+		for _, window := range rule.ScheduleWindows() {
+			for _, namespace := range rule.Namespaces {
+				upscale := cronEntries{ruleNameDescription: rule.Name, namespace: namespace.String(), overrideReplicas: rule.OverrideScaling, operation: types.OperationUpscale}
+				if err := dc.addCronJob(upscale, rule, window, dc.job(namespace, types.OperationUpscale)); err != nil {
+					scheduleErrors = append(scheduleErrors, err)
+				}
+
+				downscale := cronEntries{ruleNameDescription: rule.Name, namespace: namespace.String(), overrideReplicas: rule.OverrideScaling, operation: types.OperationDownscale}
+				if err := dc.addCronJob(downscale, rule, window, dc.job(namespace, types.OperationDownscale)); err != nil {
+					scheduleErrors = append(scheduleErrors, err)
+				}
+			}
+
+			if rule.NamespaceSelector != nil {
+				selector := metav1.FormatLabelSelector(rule.NamespaceSelector)
+
+				upscale := cronEntries{ruleNameDescription: rule.Name, namespaceSelector: selector, overrideReplicas: rule.OverrideScaling, operation: types.OperationUpscale}
+				if err := dc.addCronJob(upscale, rule, window, dc.selectorJob(index, types.OperationUpscale)); err != nil {
+					scheduleErrors = append(scheduleErrors, err)
+				}
+
+				downscale := cronEntries{ruleNameDescription: rule.Name, namespaceSelector: selector, overrideReplicas: rule.OverrideScaling, operation: types.OperationDownscale}
+				if err := dc.addCronJob(downscale, rule, window, dc.selectorJob(index, types.OperationDownscale)); err != nil {
+					scheduleErrors = append(scheduleErrors, err)
+				}
+			}
 		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	dc.cancelFunc = cancel
 
-	go dc.notifyCronEntries(ctx)
+	dc.resumeQueuedJobs(ctx)
+	go dc.notifyCronEntries(ctx, dc.cron)
 
 	dc.cron.Start()
+
+	dc.refreshNextRuns()
+	dc.setScheduleConditions(scheduleErrors)
+	dc.checkCalendars()
+	dc.publishStatus()
 }
 
-func (dc *Downscaler) notifyCronEntries(ctx context.Context) {
+func (dc *Downscaler) notifyCronEntries(ctx context.Context, c *cron.Cron) {
 	interval := dc.app.Spec.Config.CronLoggerInterval
 	if interval <= 0 {
 		interval = 300
 	}
 
-	for _, entry := range dc.cron.Entries() {
+	for _, entry := range c.Entries() {
 		if e, found := dc.cronEntriesMapping[entry.ID]; found {
 			dc.log.Info("cron",
-				"namespace", e.namespace,
+				"downscaler", dc.app.Name,
+				"namespace", e.target(),
 				"override_scaling", e.overrideReplicas,
 				"description", e.ruleNameDescription,
 				"entryID", entry.ID,
@@ -178,10 +299,11 @@ func (dc *Downscaler) notifyCronEntries(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			for _, entry := range dc.cron.Entries() {
+			for _, entry := range c.Entries() {
 				if e, found := dc.cronEntriesMapping[entry.ID]; found {
 					dc.log.Info("cron",
-						"namespace", e.namespace,
+						"downscaler", dc.app.Name,
+						"namespace", e.target(),
 						"override_scaling", e.overrideReplicas,
 						"description", e.ruleNameDescription,
 						"entryID", entry.ID,
@@ -199,6 +321,9 @@ func (s *Downscaler) Add(ctx context.Context, app downscalergov1alpha1.Downscale
 }
 
 func (dc *Downscaler) resetState() *Downscaler {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
 	if dc.cron != nil {
 		dc.cron.Stop()
 		dc.cron = nil
@@ -228,25 +353,36 @@ func (dc *Downscaler) createNewClient() error {
 	return nil
 }
 
-func (dc *Downscaler) buildCronExpression(recurrence, timeStr string) string {
-	timeStrParts := strings.Split(timeStr, ":")
-	if len(timeStrParts) == 2 {
-		timeStr = timeStr + ":00"
+// buildCronExpression returns the expression of the operation in the window, from its raw cron
+// expression or from its time and recurrence. An invalid one is an error, so the rule is reported
+// in the status instead of being scheduled at some other time.
+func (dc *Downscaler) buildCronExpression(rule downscalergov1alpha1.Rules, window downscalergov1alpha1.Window, operation types.ScalingOperation) (string, error) {
+	if operation == types.OperationDownscale {
+		return utils.ScheduleExpression(window.DownscaleCron, dc.recurrence(rule, window), window.DownscaleTime)
 	}
-	t, err := time.Parse("15:04:05", timeStr)
-	if err != nil {
-		dc.log.Error(err, "Invalid time format", "timeStr", timeStr, "error", err)
-		return "0 0 * * *"
+	return utils.ScheduleExpression(window.UpscaleCron, dc.recurrence(rule, window), window.UpscaleTime)
+}
+
+// resourceScaler returns the scaler registered for the resource type or, for the names declared in
+// scalableResources, one working through the /scale subresource.
+func (dc *Downscaler) resourceScaler(resource types.ResourceType) (factory.ResourceScaler, bool) {
+	if resourceScaler, created := (*dc.getFactory)[resource]; created {
+		return resourceScaler, true
 	}
 
-	if recurrence == "*" || recurrence == "@daily" {
-		return fmt.Sprintf("%d %d %d * * *", t.Second(), t.Minute(), t.Hour())
+	for _, scalableResource := range dc.app.Spec.DownscalerOptions.ScalableResources {
+		if scalableResource.Name == resource {
+			return factory.NewScaleSubresourceScaler(dc.client, dc.store, dc.log, scalableResource), true
+		}
 	}
 
-	return fmt.Sprintf("%d %d %d * * %s", t.Second(), t.Minute(), t.Hour(), recurrence)
+	return nil, false
 }
 
 func (dc *Downscaler) rules() []downscalergov1alpha1.Rules {
+	if dc.app.Spec.DownscalerOptions.TimeRules == nil {
+		return nil
+	}
 	return dc.app.Spec.DownscalerOptions.TimeRules.Rules
 }
 
@@ -254,6 +390,14 @@ func (dc *Downscaler) resourceScaling() []types.ResourceType {
 	return dc.app.Spec.DownscalerOptions.ResourceScaling
 }
 
-func (dc *Downscaler) recurrence() string {
+// recurrence returns the recurrence of the window, falling back to the one of the rule and then
+// to the one of the schedule.
+func (dc *Downscaler) recurrence(rule downscalergov1alpha1.Rules, window downscalergov1alpha1.Window) string {
+	if window.Recurrence != "" {
+		return window.Recurrence
+	}
+	if rule.Recurrence != "" {
+		return rule.Recurrence
+	}
 	return dc.app.Spec.Schedule.Recurrence
 }
@@ -3,6 +3,11 @@ package manager
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,11 +23,19 @@ import (
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 const (
@@ -50,8 +63,11 @@ func intializeManager(t *testing.T, c *apiclient.APIClient, downscalerObject dow
 	dm := setupDownscalerInstance(c, downscalerObject, storeClient)
 	dm.cron = cron.New(cron.WithLocation(location), cron.WithSeconds())
 
-	dm.handleDatabase()
+	if err := dm.handleDatabase(); err != nil {
+		t.Fatalf("error migrating the database: %v", err)
+	}
 	dm.initializeCronTasks()
+	dm.runCatchUp(dm.jobsContext())
 	return dm
 }
 
@@ -90,8 +106,13 @@ func createObjects(objectType any, namespaces []downscalergov1alpha1.Namespace,
 	return clientObjectList
 }
 
+// createTestScaleTime returns the times at the given offsets. It first waits for the start of the
+// next second, the times are truncated to the second and the jobs would otherwise run up to a
+// second earlier than the offsets, racing with the assertions made after oneSecond.
 func createTestScaleTime(downscaleTime, upscaleTime time.Duration) (string, string) {
 	now := time.Now()
+	<-time.After(now.Truncate(time.Second).Add(time.Second + 100*time.Millisecond).Sub(now))
+	now = time.Now()
 	if upscaleTime == -1 {
 		return now.Add(downscaleTime).Format(defaultFormatTime), ""
 	}
@@ -107,12 +128,17 @@ func createTestScaleTime(downscaleTime, upscaleTime time.Duration) (string, stri
 func setupDownscalerInstance(c *apiclient.APIClient, downscalerObject downscalergov1alpha1.Downscaler, persistence *store.Persistence) *Downscaler {
 	return (&Downscaler{}).
 		Client(c).
+		APIReader(c.Client).
 		Factory(factory.NewScalerFactory(c, persistence, logr.Logger{})).
 		Persistence(persistence).
 		Add(context.Background(), downscalerObject).
 		Logger(logr.Logger{})
 }
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
 func setupDownscalerObject(downscaleTime, upscaleTime string, ruleNameDescription string, namespaces []downscalergov1alpha1.Namespace, resourceTypes []objecttypes.ResourceType) downscalergov1alpha1.Downscaler {
 	return downscalergov1alpha1.Downscaler{
 		TypeMeta: metav1.TypeMeta{
@@ -136,6 +162,8 @@ func setupDownscalerObject(downscaleTime, upscaleTime string, ruleNameDescriptio
 							DownscaleTime:   downscaleTime,
 							UpscaleTime:     upscaleTime,
 							OverrideScaling: resourceTypes,
+							// the catch-up is covered by its own tests, the others test the cron entries.
+							CatchUp: ptr(false),
 						},
 					},
 				},
@@ -515,10 +543,84 @@ func TestLifecycleSqlite(t *testing.T) {
 				}
 				assert.Equal(t, tc.expectedReplicas, *updatedObject.Spec.Replicas)
 			}
+
+			for i := range tc.objectName {
+				events, err := storeClient.ScalingOperation.QueryEvents(context.Background(), store.ScalingEventFilter{
+					NamespaceName: tc.namespaces[i].String(),
+					ResourceName:  tc.objectName[i],
+				})
+				if err != nil {
+					t.Fatalf("error querying scaling events: %v", err)
+				}
+
+				if len(tc.overrideReplicas) > 0 {
+					assert.Empty(t, events)
+					continue
+				}
+
+				if assert.Len(t, events, 2) {
+					assert.Equal(t, "upscale", events[0].Operation)
+					assert.Equal(t, int(tc.expectedReplicas), events[0].ReplicasAfter)
+					assert.Equal(t, "downscale", events[1].Operation)
+					assert.Equal(t, int(tc.initiaReplicas), events[1].ReplicasBefore)
+					assert.Equal(t, int(tc.expectedDownscaledReplicas), events[1].ReplicasAfter)
+					assert.Equal(t, store.EventOutcomeSucceeded, events[1].Outcome)
+				}
+			}
 		})
 	}
 }
 
+// TestLifecycleMemory restores the exact replicas recorded on downscale, and falls back to the
+// upscale replicas for the objects the store has never seen, as after a restart of the pod.
+func TestLifecycleMemory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-memory1", "ns-memory2"}
+	objectNames := []string{"deployment1", "deployment2"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, objectNames, 4)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "memory rule", namespaces, nil)
+
+	storeClient := store.NewMemory()
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	for i := range objectNames {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, int32(0), *updatedObject.Spec.Replicas)
+	}
+
+	// the record of the second deployment is lost, as if the pod was restarted.
+	assert.NoError(t, storeClient.ScalingOperation.Delete(context.Background(), &store.ScalingOperation{
+		DownscalerName: store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
+		NamespaceName:  namespaces[1].String(),
+		ResourceName:   objectNames[1],
+		ResourceType:   objecttypes.DeploymentObjectResource.String(),
+	}))
+
+	<-time.After(oneSecond)
+
+	for i, expectedReplicas := range []int32{4, 1} {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expectedReplicas, *updatedObject.Spec.Replicas)
+	}
+}
+
 func TestLifecyclePostgres(t *testing.T) {
 	scheme := runtime.NewScheme()
 	_ = appsv1.AddToScheme(scheme)
@@ -650,3 +752,1511 @@ func TestLifecyclePostgres(t *testing.T) {
 		})
 	}
 }
+
+func TestDownscalerStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-status"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "status rule", namespaces, nil)
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &downscalerObject)...).
+		WithStatusSubresource(&downscalerObject).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	dm := intializeManager(t, c, downscalerObject, nil)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	updated := downscalergov1alpha1.Downscaler{}
+	if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(&downscalerObject), &updated); err != nil {
+		t.Fatalf("error getting downscaler object: %v", err)
+	}
+
+	assert.Len(t, updated.Status.Rules, 1)
+	assert.Equal(t, "status rule", updated.Status.Rules[0].Name)
+
+	namespaceStatus := updated.Status.Rules[0].Namespaces[0]
+	assert.Equal(t, "ns-status", namespaceStatus.Name)
+	assert.Equal(t, downscalergov1alpha1.PhaseDown, namespaceStatus.Phase)
+	assert.NotNil(t, namespaceStatus.LastDownscaleTime)
+	assert.Nil(t, namespaceStatus.LastUpscaleTime)
+	assert.NotNil(t, namespaceStatus.NextUpscaleTime)
+	assert.NotNil(t, namespaceStatus.NextDownscaleTime)
+
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, downscalergov1alpha1.ConditionReady))
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, downscalergov1alpha1.ConditionScheduleValid))
+}
+
+func TestSchedulerMultipleDownscalers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	platformNamespaces := []downscalergov1alpha1.Namespace{"ns-platform"}
+	productNamespaces := []downscalergov1alpha1.Namespace{"ns-product"}
+
+	clientObjectList := append(
+		createObjects(&appsv1.Deployment{}, platformNamespaces, []string{"platform-api"}, 3),
+		createObjects(&appsv1.Deployment{}, productNamespaces, []string{"product-api"}, 3)...,
+	)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Hour)
+
+	platform := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "platform rule", platformNamespaces, nil)
+	platform.Name, platform.Namespace = "platform", "team-platform"
+
+	product := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "product rule", productNamespaces, nil)
+	product.Name, product.Namespace = "product", "team-product"
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &platform, &product)...).
+		WithStatusSubresource(&platform, &product).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	scheduler := NewScheduler(setupDownscalerInstance(c, downscalergov1alpha1.Downscaler{}, nil))
+
+	for _, app := range []downscalergov1alpha1.Downscaler{platform, product} {
+		if _, err := scheduler.Schedule(context.Background(), app); err != nil {
+			t.Fatalf("error scheduling downscaler %s: %v", app.Name, err)
+		}
+	}
+
+	platformKey := client.ObjectKeyFromObject(&platform)
+	productKey := client.ObjectKeyFromObject(&product)
+
+	platformScheduler, found := scheduler.Get(platformKey)
+	assert.True(t, found)
+	productScheduler, found := scheduler.Get(productKey)
+	assert.True(t, found)
+	assert.NotSame(t, platformScheduler.cron, productScheduler.cron)
+	assert.Len(t, platformScheduler.cron.Entries(), 2)
+	assert.Len(t, productScheduler.cron.Entries(), 2)
+
+	scheduler.Remove(productKey)
+	defer scheduler.Remove(platformKey)
+
+	_, found = scheduler.Get(productKey)
+	assert.False(t, found)
+
+	<-time.After(oneSecond)
+
+	platformDeployment := &appsv1.Deployment{}
+	if err := c.Get("ns-platform", platformDeployment, "platform-api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	assert.Equal(t, int32(0), *platformDeployment.Spec.Replicas)
+
+	productDeployment := &appsv1.Deployment{}
+	if err := c.Get("ns-product", productDeployment, "product-api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	assert.Equal(t, int32(3), *productDeployment.Spec.Replicas)
+}
+
+func TestFinalizeRestoresReplicas(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-finalize1", "ns-finalize2"}
+	objectNames := []string{"statefulset1", "statefulset2"}
+
+	storeClient := store.NewMemory()
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "finalize rule", namespaces, nil)
+
+	clientObjectList := createObjects(&appsv1.StatefulSet{}, namespaces, objectNames, 4)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &downscalerObject)...).
+		WithStatusSubresource(&downscalerObject).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	scheduler := NewScheduler(setupDownscalerInstance(c, downscalergov1alpha1.Downscaler{}, storeClient))
+	if _, err := scheduler.Schedule(context.Background(), downscalerObject); err != nil {
+		t.Fatalf("error scheduling downscaler: %v", err)
+	}
+
+	<-time.After(oneSecond)
+
+	for i := range objectNames {
+		updatedObject := &appsv1.StatefulSet{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting updated statefulset: %v", err)
+		}
+		assert.Equal(t, int32(0), *updatedObject.Spec.Replicas)
+	}
+
+	if err := scheduler.Finalize(context.Background(), downscalerObject); err != nil {
+		t.Fatalf("error finalizing downscaler: %v", err)
+	}
+
+	_, found := scheduler.Get(client.ObjectKeyFromObject(&downscalerObject))
+	assert.False(t, found)
+
+	for i := range objectNames {
+		updatedObject := &appsv1.StatefulSet{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting restored statefulset: %v", err)
+		}
+		assert.Equal(t, int32(4), *updatedObject.Spec.Replicas)
+	}
+
+	scalingObjects, err := storeClient.ScalingOperation.List(context.Background(), store.ScalingOperationFilter{})
+	if err != nil {
+		t.Fatalf("error listing scaling operations: %v", err)
+	}
+	assert.Empty(t, scalingObjects)
+}
+
+func TestFinalizeRemovesRecordsOfUpNamespaces(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-finalize-up", "ns-finalize-up"}
+	objectNames := []string{"scaled-up", "scaled-down-by-hand"}
+
+	storeClient := store.NewMemory()
+
+	downscalerObject := setupDownscalerObject("", "", "finalize rule", namespaces[:1], nil)
+	downscalerObject.Status.Rules = []downscalergov1alpha1.RuleStatus{{
+		Name:       "finalize rule",
+		Namespaces: []downscalergov1alpha1.NamespaceStatus{{Name: "ns-finalize-up", Phase: downscalergov1alpha1.PhaseUp}},
+	}}
+
+	clientObjectList := append(
+		createObjects(&appsv1.Deployment{}, namespaces[:1], objectNames[:1], 5),
+		createObjects(&appsv1.Deployment{}, namespaces[1:], objectNames[1:], 0)...,
+	)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	// records left over by a cycle whose namespace is reported up.
+	for i := range objectNames {
+		if err := storeClient.ScalingOperation.Upsert(context.Background(), &store.ScalingOperation{
+			DownscalerName:      store.DownscalerKey(downscalerObject.Namespace, downscalerObject.Name),
+			NamespaceName:       namespaces[i].String(),
+			RuleNameDescription: "finalize rule",
+			ResourceName:        objectNames[i],
+			ResourceType:        objecttypes.DeploymentObjectResource.String(),
+			Replicas:            3,
+		}); err != nil {
+			t.Fatalf("error recording replicas: %v", err)
+		}
+	}
+
+	scheduler := NewScheduler(setupDownscalerInstance(c, downscalergov1alpha1.Downscaler{}, storeClient))
+	if err := scheduler.Finalize(context.Background(), downscalerObject); err != nil {
+		t.Fatalf("error finalizing downscaler: %v", err)
+	}
+
+	// the workload running more than recorded is left alone, the one running fewer is restored.
+	for name, expected := range map[string]int32{"scaled-up": 5, "scaled-down-by-hand": 3} {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-finalize-up", updatedObject, name); err != nil {
+			t.Fatalf("error getting deployment: %v", err)
+		}
+		assert.Equal(t, expected, *updatedObject.Spec.Replicas, name)
+	}
+
+	scalingObjects, err := storeClient.ScalingOperation.List(context.Background(), store.ScalingOperationFilter{})
+	if err != nil {
+		t.Fatalf("error listing scaling operations: %v", err)
+	}
+	assert.Empty(t, scalingObjects)
+}
+
+func createHPA(namespace, name, deployment string, minReplicas, maxReplicas int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deployment,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: maxReplicas,
+		},
+	}
+}
+
+func TestLifecycleHPASqlite(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-hpa1"}
+
+	dbClient, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to in memory db: %v", err)
+	}
+	dbClient.SetMaxOpenConns(1)
+	defer dbClient.Close()
+
+	storeClient := &store.Persistence{ScalingOperation: store.NewSqliteScalingOperationStore(dbClient)}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"api"}, 4)
+	clientObjectList = append(clientObjectList, createHPA("ns-hpa1", "api-hpa", "api", 3, 10))
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "hpa rule", namespaces,
+		[]objecttypes.ResourceType{objecttypes.DeploymentObjectResource, objecttypes.HorizontalPodAutoscalerObjectResource})
+
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	deployment := &appsv1.Deployment{}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := c.Get("ns-hpa1", deployment, "api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	if err := c.Get("ns-hpa1", hpa, "api-hpa"); err != nil {
+		t.Fatalf("error getting updated hpa: %v", err)
+	}
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+	assert.Equal(t, int32(1), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(1), hpa.Spec.MaxReplicas)
+
+	<-time.After(oneSecond)
+
+	if err := c.Get("ns-hpa1", deployment, "api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	if err := c.Get("ns-hpa1", hpa, "api-hpa"); err != nil {
+		t.Fatalf("error getting updated hpa: %v", err)
+	}
+	assert.Equal(t, int32(4), *deployment.Spec.Replicas)
+	assert.Equal(t, int32(3), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+}
+
+func TestHPANotPinnedInMemoryMode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-hpa-memory"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"api"}, 4)
+	clientObjectList = append(clientObjectList, createHPA("ns-hpa-memory", "api-hpa", "api", 3, 10))
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "hpa rule", namespaces,
+		[]objecttypes.ResourceType{objecttypes.DeploymentObjectResource, objecttypes.HorizontalPodAutoscalerObjectResource})
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	deployment := &appsv1.Deployment{}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := c.Get("ns-hpa-memory", deployment, "api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	if err := c.Get("ns-hpa-memory", hpa, "api-hpa"); err != nil {
+		t.Fatalf("error getting updated hpa: %v", err)
+	}
+	// the bounds would be lost on a restart of the pod, the autoscaler is left alone.
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+	assert.Equal(t, int32(3), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(10), hpa.Spec.MaxReplicas)
+}
+
+func TestUpscalingDeploymentsRespectsHPAMinReplicas(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-hpa2"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"api"}, 0)
+	clientObjectList = append(clientObjectList, createHPA("ns-hpa2", "api-hpa", "api", 3, 10))
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	_, testUpscaleTime := createTestScaleTime(-1, time.Second)
+	downscalerObject := setupDownscalerObject("", testUpscaleTime, "hpa rule", namespaces, nil)
+
+	dm := intializeManager(t, c, downscalerObject, nil)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	deployment := &appsv1.Deployment{}
+	if err := c.Get("ns-hpa2", deployment, "api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+}
+
+func TestDownscalingDeploymentsRespectsHPAMinReplicas(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-hpa3"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"api"}, 5)
+	clientObjectList = append(clientObjectList, createHPA("ns-hpa3", "api-hpa", "api", 3, 10))
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, _ := createTestScaleTime(time.Second, -1)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, "", "hpa rule", namespaces, nil)
+	downscaleReplicas := int32(2)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].DownscaleReplicas = &downscaleReplicas
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	deployment := &appsv1.Deployment{}
+	if err := c.Get("ns-hpa3", deployment, "api"); err != nil {
+		t.Fatalf("error getting updated deployment: %v", err)
+	}
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+}
+
+func createCronJob(namespace, name string, suspend bool) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			Suspend:  &suspend,
+		},
+	}
+}
+
+func TestLifecycleCronJobsSqlite(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = batchv1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-cronjob1"}
+
+	dbClient, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to in memory db: %v", err)
+	}
+	dbClient.SetMaxOpenConns(1)
+	defer dbClient.Close()
+
+	storeClient := &store.Persistence{ScalingOperation: store.NewSqliteScalingOperationStore(dbClient)}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			createCronJob("ns-cronjob1", "report", false),
+			createCronJob("ns-cronjob1", "cleanup", true),
+		).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "cronjob rule", namespaces,
+		[]objecttypes.ResourceType{objecttypes.CronJobObjectResource})
+
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	assertSuspended := func(name string, expected bool) {
+		cronJob := &batchv1.CronJob{}
+		if err := c.Get("ns-cronjob1", cronJob, name); err != nil {
+			t.Fatalf("error getting updated cronjob: %v", err)
+		}
+		assert.Equal(t, expected, *cronJob.Spec.Suspend, name)
+	}
+
+	<-time.After(oneSecond)
+
+	assertSuspended("report", true)
+	assertSuspended("cleanup", true)
+
+	<-time.After(oneSecond)
+
+	assertSuspended("report", false)
+	assertSuspended("cleanup", true)
+}
+
+func TestManualScaleDownAfterCycleSqlite(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-manual"}
+
+	dbClient, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to in memory db: %v", err)
+	}
+	dbClient.SetMaxOpenConns(1)
+	defer dbClient.Close()
+
+	storeClient := &store.Persistence{ScalingOperation: store.NewSqliteScalingOperationStore(dbClient)}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(
+			createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3),
+			createCronJob("ns-manual", "report", false),
+		)...).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	// the jobs are run by hand, the cron entries never fire during the test.
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Hour, 2*time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "manual rule", namespaces,
+		[]objecttypes.ResourceType{objecttypes.DeploymentObjectResource, objecttypes.CronJobObjectResource})
+
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	assertState := func(expectedReplicas int32, expectedSuspended bool) {
+		t.Helper()
+		deployment := &appsv1.Deployment{}
+		if err := c.Get("ns-manual", deployment, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expectedReplicas, *deployment.Spec.Replicas)
+
+		cronJob := &batchv1.CronJob{}
+		if err := c.Get("ns-manual", cronJob, "report"); err != nil {
+			t.Fatalf("error getting updated cronjob: %v", err)
+		}
+		assert.Equal(t, expectedSuspended, *cronJob.Spec.Suspend)
+	}
+
+	dm.job(namespaces[0], objecttypes.OperationDownscale)()
+	assertState(0, true)
+
+	dm.job(namespaces[0], objecttypes.OperationUpscale)()
+	assertState(3, false)
+
+	scalingObjects, err := storeClient.ScalingOperation.List(context.Background(), store.ScalingOperationFilter{})
+	if err != nil {
+		t.Fatalf("error listing scaling operations: %v", err)
+	}
+	assert.Empty(t, scalingObjects)
+
+	// scaled down by hand after the cycle, the next one leaves both objects down.
+	deployment := &appsv1.Deployment{}
+	if err := c.Get("ns-manual", deployment, "deployment1"); err != nil {
+		t.Fatalf("error getting deployment: %v", err)
+	}
+	if err := c.Patch(0, deployment); err != nil {
+		t.Fatalf("error scaling deployment: %v", err)
+	}
+	cronJob := &batchv1.CronJob{}
+	if err := c.Get("ns-manual", cronJob, "report"); err != nil {
+		t.Fatalf("error getting cronjob: %v", err)
+	}
+	if err := c.PatchSuspend(true, cronJob); err != nil {
+		t.Fatalf("error suspending cronjob: %v", err)
+	}
+
+	dm.job(namespaces[0], objecttypes.OperationDownscale)()
+	assertState(0, true)
+
+	dm.job(namespaces[0], objecttypes.OperationUpscale)()
+	assertState(0, true)
+}
+
+func createRollout(namespace, name string, replicas int64) *unstructured.Unstructured {
+	rollout := &unstructured.Unstructured{}
+	rollout.SetAPIVersion("argoproj.io/v1alpha1")
+	rollout.SetKind("Rollout")
+	rollout.SetNamespace(namespace)
+	rollout.SetName(name)
+	_ = unstructured.SetNestedField(rollout.Object, replicas, "spec", "replicas")
+	return rollout
+}
+
+// scaleSubresourceInterceptor serves the scale subresource of unstructured objects from spec.replicas,
+// the fake client only implements it for the built-in workloads.
+func scaleSubresourceInterceptor() interceptor.Funcs {
+	return interceptor.Funcs{
+		SubResourceGet: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return err
+			}
+			replicas, _, _ := unstructured.NestedInt64(obj.(*unstructured.Unstructured).Object, "spec", "replicas")
+			subResource.(*autoscalingv1.Scale).Spec.Replicas = int32(replicas)
+			subResource.(*autoscalingv1.Scale).ResourceVersion = obj.GetResourceVersion()
+			return nil
+		},
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			updateOptions := client.SubResourceUpdateOptions{}
+			updateOptions.ApplyOptions(opts)
+
+			// like the api server, the update is checked against the resourceVersion of the scale only
+			scale := updateOptions.SubResourceBody.(*autoscalingv1.Scale)
+			object := obj.(*unstructured.Unstructured)
+			if err := c.Get(ctx, client.ObjectKeyFromObject(object), object); err != nil {
+				return err
+			}
+			if scale.ResourceVersion != "" && scale.ResourceVersion != object.GetResourceVersion() {
+				return apierrors.NewConflict(schema.GroupResource{Resource: subResourceName}, object.GetName(), errors.New("the object has been modified"))
+			}
+			replicas := int64(scale.Spec.Replicas)
+			if err := unstructured.SetNestedField(object.Object, replicas, "spec", "replicas"); err != nil {
+				return err
+			}
+			return c.Update(ctx, object)
+		},
+	}
+}
+
+func TestLifecycleScalableResourcesSqlite(t *testing.T) {
+	namespaces := []downscalergov1alpha1.Namespace{"ns-rollout1"}
+
+	dbClient, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to in memory db: %v", err)
+	}
+	dbClient.SetMaxOpenConns(1)
+	defer dbClient.Close()
+
+	storeClient := &store.Persistence{ScalingOperation: store.NewSqliteScalingOperationStore(dbClient)}
+
+	fakeClient := fake.NewClientBuilder().
+		WithObjects(createRollout("ns-rollout1", "checkout", 3)).
+		WithInterceptorFuncs(scaleSubresourceInterceptor()).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "rollout rule", namespaces, []objecttypes.ResourceType{"rollouts"})
+	downscalerObject.Spec.DownscalerOptions.ScalableResources = []downscalergov1alpha1.ScalableResource{
+		{Name: "rollouts", Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"},
+	}
+
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	assertReplicas := func(expected int64) {
+		rollout := createRollout("ns-rollout1", "checkout", 0)
+		if err := c.Get("ns-rollout1", rollout, "checkout"); err != nil {
+			t.Fatalf("error getting updated rollout: %v", err)
+		}
+		replicas, _, _ := unstructured.NestedInt64(rollout.Object, "spec", "replicas")
+		assert.Equal(t, expected, replicas)
+	}
+
+	<-time.After(oneSecond)
+	assertReplicas(0)
+
+	<-time.After(oneSecond)
+	assertReplicas(3)
+}
+
+func TestLifecycleScalableResourcesAnnotations(t *testing.T) {
+	namespaces := []downscalergov1alpha1.Namespace{"ns-rollout-annotations"}
+
+	fakeClient := fake.NewClientBuilder().
+		WithObjects(createRollout("ns-rollout-annotations", "checkout", 3)).
+		WithInterceptorFuncs(scaleSubresourceInterceptor()).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	storeClient := &store.Persistence{ScalingOperation: store.NewAnnotationScalingOperationStore(fakeClient)}
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "rollout rule", namespaces, []objecttypes.ResourceType{"rollouts"})
+	downscalerObject.Spec.DownscalerOptions.ScalableResources = []downscalergov1alpha1.ScalableResource{
+		{Name: "rollouts", Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"},
+	}
+
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	getRollout := func() *unstructured.Unstructured {
+		rollout := createRollout("ns-rollout-annotations", "checkout", 0)
+		if err := c.Get("ns-rollout-annotations", rollout, "checkout"); err != nil {
+			t.Fatalf("error getting updated rollout: %v", err)
+		}
+		return rollout
+	}
+
+	<-time.After(oneSecond)
+	rollout := getRollout()
+	replicas, _, _ := unstructured.NestedInt64(rollout.Object, "spec", "replicas")
+	assert.Equal(t, int64(0), replicas)
+	assert.Equal(t, "3", rollout.GetAnnotations()[store.RecordedReplicasAnnotation])
+
+	<-time.After(oneSecond)
+	rollout = getRollout()
+	replicas, _, _ = unstructured.NestedInt64(rollout.Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+	assert.NotContains(t, rollout.GetAnnotations(), store.RecordedReplicasAnnotation)
+}
+
+func TestNamespaceSelector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-preview1", "ns-preview2", "ns-listed", "ns-other"}
+	objectNames := []string{"web", "web", "web", "web"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, objectNames, 2)
+	for _, namespace := range namespaces {
+		labels := map[string]string{"env": "preview"}
+		if namespace == "ns-other" {
+			labels = nil
+		}
+		clientObjectList = append(clientObjectList, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace.String(), Labels: labels},
+		})
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, _ := createTestScaleTime(time.Second, -1)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, "", "preview rule", nil, nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].NamespaceSelector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"env": "preview"},
+	}
+	// ns-listed matches the selector but is owned by the literal list of another rule without any schedule
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules = append(downscalerObject.Spec.DownscalerOptions.TimeRules.Rules,
+		downscalergov1alpha1.Rules{Name: "listed rule", Namespaces: []downscalergov1alpha1.Namespace{"ns-listed"}, CatchUp: ptr(false)})
+
+	dm := intializeManager(t, c, downscalerObject, nil)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	expectedReplicas := map[string]int32{"ns-preview1": 0, "ns-preview2": 0, "ns-listed": 2, "ns-other": 2}
+	for namespace, expected := range expectedReplicas {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get(namespace, updatedObject, "web"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expected, *updatedObject.Spec.Replicas, namespace)
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	for _, namespace := range []string{"ns-preview1", "ns-preview2"} {
+		s := dm.namespaceStatus("preview rule", namespace)
+		if assert.NotNil(t, s, namespace) {
+			assert.Equal(t, downscalergov1alpha1.PhaseDown, s.Phase)
+		}
+	}
+}
+
+func TestWorkloadSelectorAndExcludeAnnotation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+
+	replicas := int32(2)
+	newDeployment := func(name string, labels, annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns-workloads", Labels: labels, Annotations: annotations},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newDeployment("web", map[string]string{"tier": "app"}, nil),
+			newDeployment("vault-agent-injector", map[string]string{"tier": "app"}, map[string]string{factory.ExcludeAnnotation: "true"}),
+			newDeployment("ingress-nginx", map[string]string{"tier": "edge"}, nil),
+		).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, _ := createTestScaleTime(time.Second, -1)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, "", "workload rule", []downscalergov1alpha1.Namespace{"ns-workloads"}, nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].WorkloadSelector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"tier": "app"},
+	}
+
+	dm := intializeManager(t, c, downscalerObject, nil)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	expectedReplicas := map[string]int32{"web": 0, "vault-agent-injector": 2, "ingress-nginx": 2}
+	for name, expected := range expectedReplicas {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-workloads", updatedObject, name); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expected, *updatedObject.Spec.Replicas, name)
+	}
+}
+
+func TestReplicaOverrides(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+
+	replicas := int32(4)
+	newDeployment := func(name string, annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns-replicas", Annotations: annotations},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newDeployment("api", nil),
+			newDeployment("health", map[string]string{
+				factory.DownscaleReplicasAnnotation: "2",
+				factory.UpscaleReplicasAnnotation:   "5",
+			}),
+		).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "replicas rule", []downscalergov1alpha1.Namespace{"ns-replicas"}, nil)
+	downscaleReplicas, upscaleReplicas := int32(1), int32(3)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].DownscaleReplicas = &downscaleReplicas
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].UpscaleReplicas = &upscaleReplicas
+
+	dm := intializeManager(t, c, downscalerObject, nil)
+	defer dm.cron.Stop()
+
+	assertReplicas := func(expectedReplicas map[string]int32) {
+		for name, expected := range expectedReplicas {
+			updatedObject := &appsv1.Deployment{}
+			if err := c.Get("ns-replicas", updatedObject, name); err != nil {
+				t.Fatalf("error getting updated deployment: %v", err)
+			}
+			assert.Equal(t, expected, *updatedObject.Spec.Replicas, name)
+		}
+	}
+
+	<-time.After(oneSecond)
+	assertReplicas(map[string]int32{"api": 1, "health": 2})
+
+	<-time.After(oneSecond)
+	assertReplicas(map[string]int32{"api": 3, "health": 5})
+}
+
+func TestLifecycleAnnotations(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-annotations1", "ns-annotations2"}
+	objectNames := []string{"deployment1", "deployment2"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, objectNames, 5)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	storeClient := &store.Persistence{ScalingOperation: store.NewAnnotationScalingOperationStore(fakeClient)}
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "annotations rule", namespaces, nil)
+
+	dm := intializeManager(t, c, downscalerObject, storeClient)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	for i := range objectNames {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, int32(0), *updatedObject.Spec.Replicas)
+		assert.Equal(t, "5", updatedObject.Annotations[store.RecordedReplicasAnnotation])
+	}
+
+	<-time.After(oneSecond)
+
+	for i := range objectNames {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, int32(5), *updatedObject.Spec.Replicas)
+	}
+}
+
+// unavailableDatabase wraps the memory store with a database that answers only once marked available.
+type unavailableDatabase struct {
+	*store.MemoryScalingOperationStore
+	available atomic.Bool
+}
+
+func (s *unavailableDatabase) Ping(ctx context.Context) error {
+	if !s.available.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestJobsQueuedUntilDatabaseAvailable(t *testing.T) {
+	defer func(interval time.Duration) { databaseRetryInterval = interval }(databaseRetryInterval)
+	databaseRetryInterval = 200 * time.Millisecond
+
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-database-down"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "database rule", namespaces, nil)
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &downscalerObject)...).
+		WithStatusSubresource(&downscalerObject).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	database := &unavailableDatabase{MemoryScalingOperationStore: store.NewMemoryScalingOperationStore()}
+	dm := intializeManager(t, c, downscalerObject, &store.Persistence{ScalingOperation: database})
+	defer dm.cron.Stop()
+
+	getStatus := func() downscalergov1alpha1.DownscalerStatus {
+		updated := downscalergov1alpha1.Downscaler{}
+		if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(&downscalerObject), &updated); err != nil {
+			t.Fatalf("error getting downscaler object: %v", err)
+		}
+		return updated.Status
+	}
+	getReplicas := func() int32 {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-database-down", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		return *updatedObject.Spec.Replicas
+	}
+
+	<-time.After(oneSecond)
+
+	assert.Equal(t, int32(3), getReplicas())
+	status := getStatus()
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, downscalergov1alpha1.ConditionDatabaseAvailable))
+	assert.Contains(t, status.Rules[0].Namespaces[0].Message, "queued until the database is available")
+
+	database.available.Store(true)
+	<-time.After(3 * databaseRetryInterval)
+
+	assert.Equal(t, int32(0), getReplicas())
+	status = getStatus()
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, downscalergov1alpha1.ConditionDatabaseAvailable))
+	assert.Equal(t, downscalergov1alpha1.PhaseDown, status.Rules[0].Namespaces[0].Phase)
+	assert.Empty(t, status.Rules[0].Namespaces[0].Message)
+}
+
+// unbootstrappedDatabase also fails the schema migration while the database is unavailable.
+type unbootstrappedDatabase struct {
+	*unavailableDatabase
+}
+
+func (s unbootstrappedDatabase) Bootstrap(ctx context.Context) error {
+	if err := s.Ping(ctx); err != nil {
+		return err
+	}
+	return s.unavailableDatabase.Bootstrap(ctx)
+}
+
+func TestQueuedJobsSurviveReconcile(t *testing.T) {
+	defer func(interval time.Duration) { databaseRetryInterval = interval }(databaseRetryInterval)
+	databaseRetryInterval = 200 * time.Millisecond
+
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-database-reconcile"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "database rule", namespaces, nil)
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &downscalerObject)...).
+		WithStatusSubresource(&downscalerObject).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	database := unbootstrappedDatabase{&unavailableDatabase{MemoryScalingOperationStore: store.NewMemoryScalingOperationStore()}}
+	scheduler := NewScheduler(setupDownscalerInstance(c, downscalergov1alpha1.Downscaler{}, &store.Persistence{ScalingOperation: database}))
+	defer scheduler.Remove(client.ObjectKeyFromObject(&downscalerObject))
+
+	if _, err := scheduler.Schedule(context.Background(), downscalerObject); err != nil {
+		t.Fatalf("error scheduling downscaler with the database down: %v", err)
+	}
+
+	getReplicas := func() int32 {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-database-reconcile", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		return *updatedObject.Spec.Replicas
+	}
+
+	<-time.After(oneSecond)
+	assert.Equal(t, int32(3), getReplicas())
+
+	if _, err := scheduler.Schedule(context.Background(), downscalerObject); err != nil {
+		t.Fatalf("error reconciling downscaler: %v", err)
+	}
+
+	dc, found := scheduler.Get(client.ObjectKeyFromObject(&downscalerObject))
+	if !found {
+		t.Fatal("downscaler not scheduled")
+	}
+	dc.mu.Lock()
+	assert.Len(t, dc.queuedJobs, 1)
+	dc.mu.Unlock()
+
+	database.available.Store(true)
+	<-time.After(3 * databaseRetryInterval)
+
+	assert.Equal(t, int32(0), getReplicas())
+
+	updated := downscalergov1alpha1.Downscaler{}
+	if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(&downscalerObject), &updated); err != nil {
+		t.Fatalf("error getting downscaler object: %v", err)
+	}
+	assert.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, downscalergov1alpha1.ConditionDatabaseAvailable))
+	assert.Equal(t, downscalergov1alpha1.PhaseDown, updated.Status.Rules[0].Namespaces[0].Phase)
+}
+
+func TestRuleTimeZoneAndRecurrence(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-lisbon", "ns-weekend"}
+	objectNames := []string{"deployment1", "deployment2"}
+
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, objectNames, 3)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Fatalf("error loading timezone location: %v", err)
+	}
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatalf("error loading timezone location: %v", err)
+	}
+
+	now := time.Now()
+	// the first rule runs in a second on the clock of lisbon, the second one only tomorrow.
+	downscalerObject := setupDownscalerObject(
+		now.In(lisbon).Add(time.Second).Format(defaultFormatTime), now.In(lisbon).Add(time.Hour).Format(defaultFormatTime),
+		"lisbon rule", namespaces[:1], nil,
+	)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].TimeZone = "Europe/Lisbon"
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules = append(downscalerObject.Spec.DownscalerOptions.TimeRules.Rules, downscalergov1alpha1.Rules{
+		Name:          "weekend rule",
+		Namespaces:    namespaces[1:],
+		DownscaleTime: now.In(saoPaulo).Add(time.Second).Format(defaultFormatTime),
+		UpscaleTime:   now.In(saoPaulo).Add(time.Hour).Format(defaultFormatTime),
+		Recurrence:    strconv.Itoa(int(now.In(saoPaulo).Add(24 * time.Hour).Weekday())),
+		CatchUp:       ptr(false),
+	})
+
+	dm := intializeManager(t, c, downscalerObject, nil)
+	defer dm.cron.Stop()
+
+	<-time.After(oneSecond)
+
+	for i, expectedReplicas := range []int32{0, 3} {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get(namespaces[i].String(), updatedObject, objectNames[i]); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expectedReplicas, *updatedObject.Spec.Replicas, namespaces[i].String())
+	}
+}
+
+func TestRuleWindows(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-windows"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "windows rule", namespaces, nil)
+
+	later := func(timeStr string) string {
+		t, _ := time.Parse(defaultFormatTime, timeStr)
+		return t.Add(2 * time.Second).Format(defaultFormatTime)
+	}
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].Windows = []downscalergov1alpha1.Window{
+		{DownscaleTime: later(testDownscaleTime), UpscaleTime: later(testUpscaleTime)},
+	}
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer dm.cron.Stop()
+
+	for _, expectedReplicas := range []int32{0, 3, 0, 3} {
+		<-time.After(oneSecond)
+
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-windows", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expectedReplicas, *updatedObject.Spec.Replicas)
+	}
+}
+
+func TestRuleRawCron(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-raw-cron"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, append(namespaces, "ns-broken-cron"), []string{"deployment1"}, 3)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject("", "", "raw cron rule", namespaces, nil)
+
+	cronExpression := func(timeStr string) string {
+		t, _ := time.Parse(defaultFormatTime, timeStr)
+		return fmt.Sprintf("%d %d %d * * *", t.Second(), t.Minute(), t.Hour())
+	}
+	rules := downscalerObject.Spec.DownscalerOptions.TimeRules.Rules
+	rules[0].DownscaleCron = cronExpression(testDownscaleTime)
+	rules[0].UpscaleCron = cronExpression(testUpscaleTime)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules = append(rules, downscalergov1alpha1.Rules{
+		Name:          "broken cron rule",
+		Namespaces:    []downscalergov1alpha1.Namespace{"ns-broken-cron"},
+		DownscaleCron: "0 61 * * *",
+		UpscaleCron:   "@hourly",
+		CatchUp:       ptr(false),
+	})
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &downscalerObject)...).
+		WithStatusSubresource(&downscalerObject).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer dm.cron.Stop()
+
+	for _, expectedReplicas := range []int32{0, 3} {
+		<-time.After(oneSecond)
+
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-raw-cron", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		assert.Equal(t, expectedReplicas, *updatedObject.Spec.Replicas)
+	}
+
+	updated := downscalergov1alpha1.Downscaler{}
+	if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(&downscalerObject), &updated); err != nil {
+		t.Fatalf("error getting downscaler object: %v", err)
+	}
+
+	condition := meta.FindStatusCondition(updated.Status.Conditions, downscalergov1alpha1.ConditionScheduleValid)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Contains(t, condition.Message, `rule "broken cron rule" namespace ns-broken-cron`)
+		assert.Contains(t, condition.Message, `invalid cron expression "0 0 61 * * *"`)
+	}
+}
+
+func TestCalendarExceptions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	location, _ := time.LoadLocation("America/Sao_Paulo")
+	today := time.Now().In(location)
+
+	calendarConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "holidays", Namespace: "downscaler-ns-test"},
+		Data: map[string]string{"holidays.ics": strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"SUMMARY:Public holiday",
+			"DTSTART;VALUE=DATE:" + today.Format("20060102"),
+			"END:VEVENT",
+			"BEGIN:VEVENT",
+			"SUMMARY:Weekly standup",
+			"DTSTART:" + today.Format("20060102") + "T090000",
+			"RRULE:FREQ=WEEKLY",
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")},
+	}
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-calendar-up", "ns-calendar-down"}
+	clientObjectList := append(createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1", "deployment1"}, 3), calendarConfigMap)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "release rule", namespaces[:1], nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules = append(downscalerObject.Spec.DownscalerOptions.TimeRules.Rules, downscalergov1alpha1.Rules{
+		Name:          "holiday rule",
+		Namespaces:    namespaces[1:],
+		DownscaleTime: testDownscaleTime,
+		UpscaleTime:   testUpscaleTime,
+		CatchUp:       ptr(false),
+	})
+	downscalerObject.Spec.Calendars = []downscalergov1alpha1.Calendar{
+		{Name: "release", Rules: []string{"release rule"}, Exceptions: []downscalergov1alpha1.CalendarException{
+			{Date: today.Format(time.DateOnly), Action: downscalergov1alpha1.CalendarActionForceUp, Description: "release night"},
+		}},
+		{Name: "holidays", Rules: []string{"holiday rule"}, ICS: &downscalergov1alpha1.ICSSource{
+			ConfigMapRef: downscalergov1alpha1.ConfigMapKeyRef{Name: "holidays", Key: "holidays.ics"},
+			Action:       downscalergov1alpha1.CalendarActionForceDown,
+		}},
+	}
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer dm.cron.Stop()
+
+	// the release night skips the downscale, the holiday skips the upscale.
+	for _, expected := range []map[string]int32{
+		{"ns-calendar-up": 3, "ns-calendar-down": 0},
+		{"ns-calendar-down": 0},
+	} {
+		<-time.After(oneSecond)
+
+		for namespace, expectedReplicas := range expected {
+			updatedObject := &appsv1.Deployment{}
+			if err := c.Get(namespace, updatedObject, "deployment1"); err != nil {
+				t.Fatalf("error getting updated deployment: %v", err)
+			}
+			assert.Equal(t, expectedReplicas, *updatedObject.Spec.Replicas, namespace)
+		}
+	}
+
+	// the unsupported weekly event is left out of the holidays and reported.
+	dm.mu.Lock()
+	condition := meta.FindStatusCondition(dm.status.Conditions, downscalergov1alpha1.ConditionCalendarsValid)
+	dm.mu.Unlock()
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Contains(t, condition.Message, "Weekly standup")
+	}
+}
+
+func TestICSConfigMapOfAnotherNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	foreignConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "holidays", Namespace: "kube-system"},
+		Data:       map[string]string{"holidays.ics": "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(foreignConfigMap).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(time.Second, time.Second*2)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "holiday rule", []downscalergov1alpha1.Namespace{"ns-calendar-foreign"}, nil)
+	dm := setupDownscalerInstance(c, downscalerObject, store.NewMemory())
+
+	// an object admitted before the webhook checked the namespace must not read the ConfigMap.
+	_, err := dm.icsEvents(&downscalergov1alpha1.ICSSource{
+		ConfigMapRef: downscalergov1alpha1.ConfigMapKeyRef{Name: "holidays", Namespace: "kube-system", Key: "holidays.ics"},
+		Action:       downscalergov1alpha1.CalendarActionForceDown,
+	}, time.UTC)
+	assert.ErrorContains(t, err, "is not in the namespace of the downscaler")
+
+	_, err = dm.icsEvents(&downscalergov1alpha1.ICSSource{
+		ConfigMapRef: downscalergov1alpha1.ConfigMapKeyRef{Name: "holidays", Key: "holidays.ics"},
+		Action:       downscalergov1alpha1.CalendarActionForceDown,
+	}, time.UTC)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestForcedDayKeepsRecordedReplicas(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	location, _ := time.LoadLocation("America/Sao_Paulo")
+	today := time.Now().In(location)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-calendar-forced"}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	testDownscaleTime, _ := createTestScaleTime(time.Second, -1)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, "", "holiday rule", namespaces, nil)
+	downscalerObject.Spec.Calendars = []downscalergov1alpha1.Calendar{
+		{Name: "holidays", Exceptions: []downscalergov1alpha1.CalendarException{
+			{Date: today.Format(time.DateOnly), Action: downscalergov1alpha1.CalendarActionForceDown, Description: "holiday"},
+		}},
+	}
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer dm.cron.Stop()
+
+	getReplicas := func() int32 {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-calendar-forced", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		return *updatedObject.Spec.Replicas
+	}
+
+	<-time.After(oneSecond)
+	assert.Equal(t, int32(0), getReplicas())
+
+	// the start of the forced day and a second downscale leave the namespace already down alone.
+	dm.calendarJob(0)()
+	dm.job(namespaces[0], objecttypes.OperationDownscale)()
+	assert.Equal(t, int32(0), getReplicas())
+
+	dm.app.Spec.Calendars = nil
+	dm.job(namespaces[0], objecttypes.OperationUpscale)()
+	assert.Equal(t, int32(3), getReplicas())
+}
+
+func TestCatchUp(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-catch-up", "ns-no-catch-up"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1", "deployment1"}, 3)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clientObjectList...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	// the downscale already ran an hour ago, the upscale runs in an hour.
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(-time.Hour, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "catch-up rule", namespaces[:1], nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].CatchUp = ptr(true)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules = append(downscalerObject.Spec.DownscalerOptions.TimeRules.Rules, downscalergov1alpha1.Rules{
+		Name:          "no catch-up rule",
+		Namespaces:    namespaces[1:],
+		DownscaleTime: testDownscaleTime,
+		UpscaleTime:   testUpscaleTime,
+		CatchUp:       ptr(false),
+	})
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer func() { dm.cron.Stop() }()
+
+	assertReplicas := func(expected map[string]int32) {
+		t.Helper()
+		for namespace, expectedReplicas := range expected {
+			updatedObject := &appsv1.Deployment{}
+			if err := c.Get(namespace, updatedObject, "deployment1"); err != nil {
+				t.Fatalf("error getting updated deployment: %v", err)
+			}
+			assert.Equal(t, expectedReplicas, *updatedObject.Spec.Replicas, namespace)
+		}
+	}
+
+	assertReplicas(map[string]int32{"ns-catch-up": 0, "ns-no-catch-up": 3})
+	assert.Equal(t, downscalergov1alpha1.PhaseDown, dm.namespaceStatus("catch-up rule", "ns-catch-up").Phase)
+
+	// an edit moving the upscale before now brings the namespace back up on the reconcile.
+	dm.app.Spec.DownscalerOptions.TimeRules.Rules[0].DownscaleTime = testUpscaleTime
+	dm.app.Spec.DownscalerOptions.TimeRules.Rules[0].UpscaleTime = testDownscaleTime
+	dm.app.Status = dm.status
+	dm.cron.Stop()
+	dm = intializeManager(t, c, dm.app, dm.store)
+
+	assertReplicas(map[string]int32{"ns-catch-up": 3, "ns-no-catch-up": 3})
+}
+
+func TestCatchUpAfterRuleRename(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-rename"}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	// the downscale already ran an hour ago, the upscale runs in an hour.
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(-time.Hour, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "rename rule", namespaces, nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].CatchUp = ptr(true)
+
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer func() { dm.cron.Stop() }()
+
+	getReplicas := func() int32 {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-rename", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		return *updatedObject.Spec.Replicas
+	}
+
+	assert.Equal(t, int32(0), getReplicas())
+
+	// the renamed rule has no phase yet and is caught up again, over the namespace already down.
+	dm.app.Spec.DownscalerOptions.TimeRules.Rules[0].Name = "renamed rule"
+	dm.app.Status = dm.status
+	dm.cron.Stop()
+	dm = intializeManager(t, c, dm.app, dm.store)
+
+	assert.Equal(t, int32(0), getReplicas())
+	assert.Equal(t, downscalergov1alpha1.PhaseDown, dm.namespaceStatus("renamed rule", "ns-rename").Phase)
+
+	dm.job(namespaces[0], objecttypes.OperationUpscale)()
+	assert.Equal(t, int32(3), getReplicas())
+}
+
+func TestCatchUpFailedNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-catch-up-failed"}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)...).Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	// the downscale already ran an hour ago, the upscale runs in an hour.
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(-time.Hour, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "failed rule", namespaces, nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].CatchUp = ptr(true)
+
+	failed := func() downscalergov1alpha1.DownscalerStatus {
+		return downscalergov1alpha1.DownscalerStatus{Rules: []downscalergov1alpha1.RuleStatus{{
+			Name:       "failed rule",
+			Namespaces: []downscalergov1alpha1.NamespaceStatus{{Name: "ns-catch-up-failed", Phase: downscalergov1alpha1.PhaseFailed}},
+		}}}
+	}
+
+	getReplicas := func() int32 {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-catch-up-failed", updatedObject, "deployment1"); err != nil {
+			t.Fatalf("error getting updated deployment: %v", err)
+		}
+		return *updatedObject.Spec.Replicas
+	}
+
+	// the downscale that failed is run again.
+	downscalerObject.Status = failed()
+	dm := intializeManager(t, c, downscalerObject, store.NewMemory())
+	defer func() { dm.cron.Stop() }()
+
+	assert.Equal(t, int32(0), getReplicas())
+	assert.Equal(t, downscalergov1alpha1.PhaseDown, dm.namespaceStatus("failed rule", "ns-catch-up-failed").Phase)
+
+	// so is an upscale that failed.
+	dm.app.Spec.DownscalerOptions.TimeRules.Rules[0].DownscaleTime = testUpscaleTime
+	dm.app.Spec.DownscalerOptions.TimeRules.Rules[0].UpscaleTime = testDownscaleTime
+	dm.app.Status = failed()
+	dm.cron.Stop()
+	dm = intializeManager(t, c, dm.app, dm.store)
+
+	assert.Equal(t, int32(3), getReplicas())
+	assert.Equal(t, downscalergov1alpha1.PhaseUp, dm.namespaceStatus("failed rule", "ns-catch-up-failed").Phase)
+}
+
+func TestCatchUpOutsideSchedulerLock(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = downscalergov1alpha1.AddToScheme(scheme)
+
+	namespaces := []downscalergov1alpha1.Namespace{"ns-catch-up-lock"}
+	clientObjectList := createObjects(&appsv1.Deployment{}, namespaces, []string{"deployment1"}, 3)
+
+	// the downscale already ran an hour ago, the upscale runs in an hour.
+	testDownscaleTime, testUpscaleTime := createTestScaleTime(-time.Hour, time.Hour)
+	downscalerObject := setupDownscalerObject(testDownscaleTime, testUpscaleTime, "lock rule", namespaces, nil)
+	downscalerObject.Spec.DownscalerOptions.TimeRules.Rules[0].CatchUp = ptr(true)
+
+	// the patch of the deployment hangs until released, like a slow api server.
+	release := make(chan struct{})
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(clientObjectList, &downscalerObject)...).
+		WithStatusSubresource(&downscalerObject).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if _, ok := obj.(*appsv1.Deployment); ok {
+					<-release
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	c := apiclient.NewAPIClient(fakeClient)
+
+	scheduler := NewScheduler(setupDownscalerInstance(c, downscalergov1alpha1.Downscaler{}, store.NewMemory()))
+	defer scheduler.Remove(client.ObjectKeyFromObject(&downscalerObject))
+
+	scheduled := make(chan error, 1)
+	go func() {
+		_, err := scheduler.Schedule(context.Background(), downscalerObject)
+		scheduled <- err
+	}()
+
+	select {
+	case err := <-scheduled:
+		assert.NoError(t, err)
+	case <-time.After(oneSecond):
+		close(release)
+		t.Fatal("the reconcile waited for the catch-up")
+	}
+
+	_, found := scheduler.Get(client.ObjectKeyFromObject(&downscalerObject))
+	assert.True(t, found)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		updatedObject := &appsv1.Deployment{}
+		if err := c.Get("ns-catch-up-lock", updatedObject, "deployment1"); err != nil {
+			return false
+		}
+		return *updatedObject.Spec.Replicas == 0
+	}, 3*oneSecond, 50*time.Millisecond)
+}
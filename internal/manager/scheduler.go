@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"context"
+	"sync"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Scheduler keeps one Downscaler per object, keyed by its NamespacedName, so every
+// Downscaler CR owns an isolated cron set that is reconciled and torn down on its own.
+type Scheduler struct {
+	template *Downscaler
+
+	mu          sync.Mutex
+	downscalers map[k8stypes.NamespacedName]*Downscaler
+}
+
+// NewScheduler uses the dependencies configured in template (client, api reader,
+// factory, persistence and logger) for every Downscaler it creates.
+func NewScheduler(template *Downscaler) *Scheduler {
+	return &Scheduler{
+		template:    template,
+		downscalers: make(map[k8stypes.NamespacedName]*Downscaler),
+	}
+}
+
+// Schedule replaces the cron set of the object with one built from its current spec.
+// A fresh Downscaler is created on every call, so jobs still running from the previous
+// spec never observe the new one halfway through.
+func (s *Scheduler) Schedule(ctx context.Context, app downscalergov1alpha1.Downscaler) (ctrl.Result, error) {
+	key := k8stypes.NamespacedName{Name: app.Name, Namespace: app.Namespace}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the jobs waiting for the database outlive the cron set, they run against the new spec.
+	var queued map[queuedJob]types.ScalingOperation
+	if previous, found := s.downscalers[key]; found {
+		previous.resetState()
+		queued = previous.takeQueuedJobs()
+	}
+	s.stop(key)
+
+	dc := s.newDownscaler().Add(ctx, app)
+	dc.queuedJobs = queued
+	if valid := dc.Validate(); !valid {
+		return ctrl.Result{}, nil
+	}
+
+	result, err := dc.Run()
+	if err != nil {
+		dc.resetState()
+		return result, err
+	}
+
+	s.downscalers[key] = dc
+	return result, nil
+}
+
+// Remove stops every cron job of the object and forgets it.
+func (s *Scheduler) Remove(key k8stypes.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stop(key)
+}
+
+// Get returns the running Downscaler of the object, if any.
+func (s *Scheduler) Get(key k8stypes.NamespacedName) (*Downscaler, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dc, found := s.downscalers[key]
+	return dc, found
+}
+
+func (s *Scheduler) stop(key k8stypes.NamespacedName) {
+	if dc, found := s.downscalers[key]; found {
+		dc.resetState()
+		delete(s.downscalers, key)
+		dc.log.Info("scheduler", "downscaler", key.String(), "status", "stopped")
+	}
+}
+
+func (s *Scheduler) newDownscaler() *Downscaler {
+	return (&Downscaler{}).
+		Client(s.template.client).
+		APIReader(s.template.apiReader).
+		Factory(s.template.getFactory).
+		Persistence(s.template.store).
+		Logger(s.template.log)
+}
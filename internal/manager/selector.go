@@ -0,0 +1,66 @@
+package manager
+
+import (
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// selectorJob scales every namespace matched by the namespaceSelector of the rule. The namespaces
+// are listed when the job runs, not when it is scheduled.
+func (dc *Downscaler) selectorJob(ruleIndex int, defaultScaleReplicas types.ScalingOperation) func() {
+	return func() {
+		rule := dc.rules()[ruleIndex]
+
+		namespaces, err := dc.selectNamespaces(rule)
+		if err != nil {
+			dc.log.Error(err, "job", "rule", rule.Name, "selecting namespaces error", err)
+			return
+		}
+
+		dc.log.Info("job", "rule", rule.Name, "selector", metav1.FormatLabelSelector(rule.NamespaceSelector), "selected namespaces", namespaces)
+
+		for _, namespace := range namespaces {
+			if dc.allowedByCalendar(rule, namespace, defaultScaleReplicas) {
+				dc.scaleNamespace(rule, namespace, defaultScaleReplicas)
+			}
+		}
+
+		dc.refreshNextRuns()
+		dc.publishStatus()
+	}
+}
+
+// selectNamespaces resolves the namespaceSelector of the rule, skipping terminating namespaces
+// and the ones listed literally by any rule, which are scaled by their own cron entries.
+func (dc *Downscaler) selectNamespaces(rule downscalergov1alpha1.Rules) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(rule.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceList, err := dc.client.GetNamespaces(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, namespace := range namespaceList.Items {
+		if namespace.Status.Phase == corev1.NamespaceTerminating || dc.listedNamespace(namespace.Name) {
+			continue
+		}
+		namespaces = append(namespaces, namespace.Name)
+	}
+
+	return namespaces, nil
+}
+
+func (dc *Downscaler) listedNamespace(namespace string) bool {
+	for _, rule := range dc.rules() {
+		if downscalergov1alpha1.Namespace(namespace).Found(rule.Namespaces) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package manager
+
+import (
+	"errors"
+	"time"
+
+	downscalergov1alpha1 "github.com/adalbertjnr/kubetime-scaler/api/v1alpha1"
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	reasonScheduled        = "Scheduled"
+	reasonSchedulingFailed = "SchedulingFailed"
+	reasonValidationFailed = "ValidationFailed"
+)
+
+// initializeStatus rebuilds the rule/namespace tree from the current spec. Phases and
+// timestamps already reported in the object status are carried over so a reconcile
+// does not wipe the scaling history.
+func (dc *Downscaler) initializeStatus() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	previous := make(map[string]downscalergov1alpha1.NamespaceStatus)
+	for _, rule := range dc.app.Status.Rules {
+		for _, namespace := range rule.Namespaces {
+			previous[rule.Name+"/"+namespace.Name] = namespace
+		}
+	}
+
+	rules := make([]downscalergov1alpha1.RuleStatus, 0, len(dc.rules()))
+	for _, rule := range dc.rules() {
+		ruleStatus := downscalergov1alpha1.RuleStatus{Name: rule.Name}
+		for _, namespace := range rule.Namespaces {
+			namespaceStatus := downscalergov1alpha1.NamespaceStatus{Name: namespace.String()}
+			if p, found := previous[rule.Name+"/"+namespace.String()]; found {
+				namespaceStatus.Phase = p.Phase
+				namespaceStatus.LastDownscaleTime = p.LastDownscaleTime
+				namespaceStatus.LastUpscaleTime = p.LastUpscaleTime
+				namespaceStatus.Message = p.Message
+			}
+			ruleStatus.Namespaces = append(ruleStatus.Namespaces, namespaceStatus)
+		}
+
+		// namespaces matched by the selector are only known after a job ran, keep the ones already reported.
+		if rule.NamespaceSelector != nil {
+			for _, previousRule := range dc.app.Status.Rules {
+				if previousRule.Name != rule.Name {
+					continue
+				}
+				for _, namespace := range previousRule.Namespaces {
+					if !downscalergov1alpha1.Namespace(namespace.Name).Found(rule.Namespaces) {
+						ruleStatus.Namespaces = append(ruleStatus.Namespaces, namespace)
+					}
+				}
+			}
+		}
+
+		rules = append(rules, ruleStatus)
+	}
+
+	dc.status.Rules = rules
+	dc.status.ObservedGeneration = dc.app.Generation
+	dc.status.Conditions = append([]metav1.Condition(nil), dc.app.Status.Conditions...)
+}
+
+func (dc *Downscaler) namespaceStatus(ruleName, namespace string) *downscalergov1alpha1.NamespaceStatus {
+	for i := range dc.status.Rules {
+		if dc.status.Rules[i].Name != ruleName {
+			continue
+		}
+		for j := range dc.status.Rules[i].Namespaces {
+			if dc.status.Rules[i].Namespaces[j].Name == namespace {
+				return &dc.status.Rules[i].Namespaces[j]
+			}
+		}
+	}
+	return nil
+}
+
+// ensureNamespaceStatus returns the status of the namespace, adding it to the rule when it was
+// matched by a namespaceSelector and is not tracked yet.
+func (dc *Downscaler) ensureNamespaceStatus(ruleName, namespace string) *downscalergov1alpha1.NamespaceStatus {
+	if s := dc.namespaceStatus(ruleName, namespace); s != nil {
+		return s
+	}
+
+	for i := range dc.status.Rules {
+		if dc.status.Rules[i].Name == ruleName {
+			dc.status.Rules[i].Namespaces = append(dc.status.Rules[i].Namespaces, downscalergov1alpha1.NamespaceStatus{Name: namespace})
+			return dc.namespaceStatus(ruleName, namespace)
+		}
+	}
+	return nil
+}
+
+// selectedNamespaceStatuses returns the namespaces of the rule that were matched by its selector.
+func (dc *Downscaler) selectedNamespaceStatuses(ruleName string) []*downscalergov1alpha1.NamespaceStatus {
+	var listed []downscalergov1alpha1.Namespace
+	for _, rule := range dc.rules() {
+		if rule.Name == ruleName {
+			listed = append(listed, rule.Namespaces...)
+		}
+	}
+
+	var selected []*downscalergov1alpha1.NamespaceStatus
+	for i := range dc.status.Rules {
+		if dc.status.Rules[i].Name != ruleName {
+			continue
+		}
+		for j := range dc.status.Rules[i].Namespaces {
+			if !downscalergov1alpha1.Namespace(dc.status.Rules[i].Namespaces[j].Name).Found(listed) {
+				selected = append(selected, &dc.status.Rules[i].Namespaces[j])
+			}
+		}
+	}
+	return selected
+}
+
+func (dc *Downscaler) recordTransition(ruleName, namespace string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if s := dc.ensureNamespaceStatus(ruleName, namespace); s != nil {
+		s.Phase = downscalergov1alpha1.PhaseTransitioning
+		s.Message = ""
+	}
+}
+
+func (dc *Downscaler) recordResult(ruleName, namespace string, operation types.ScalingOperation, err error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	s := dc.namespaceStatus(ruleName, namespace)
+	if s == nil {
+		return
+	}
+
+	if err != nil {
+		s.Phase = downscalergov1alpha1.PhaseFailed
+		s.Message = err.Error()
+		return
+	}
+
+	now := metav1.Now()
+	s.Message = ""
+	switch operation {
+	case types.OperationDownscale:
+		s.Phase = downscalergov1alpha1.PhaseDown
+		s.LastDownscaleTime = &now
+	case types.OperationUpscale:
+		s.Phase = downscalergov1alpha1.PhaseUp
+		s.LastUpscaleTime = &now
+	}
+}
+
+// refreshNextRuns copies the next activation of every tracked cron entry into the
+// namespace status of the rule that owns it. A rule with several windows reports the
+// earliest activation of all of them.
+func (dc *Downscaler) refreshNextRuns() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if dc.cron == nil {
+		return
+	}
+
+	for i := range dc.status.Rules {
+		for j := range dc.status.Rules[i].Namespaces {
+			dc.status.Rules[i].Namespaces[j].NextDownscaleTime = nil
+			dc.status.Rules[i].Namespaces[j].NextUpscaleTime = nil
+		}
+	}
+
+	for _, entry := range dc.cron.Entries() {
+		e, found := dc.cronEntriesMapping[entry.ID]
+		if !found || entry.Next.IsZero() {
+			continue
+		}
+
+		statuses := dc.selectedNamespaceStatuses(e.ruleNameDescription)
+		if e.namespaceSelector == "" {
+			statuses = []*downscalergov1alpha1.NamespaceStatus{dc.namespaceStatus(e.ruleNameDescription, e.namespace)}
+		}
+
+		next := metav1.NewTime(entry.Next)
+		for _, s := range statuses {
+			if s == nil {
+				continue
+			}
+			switch e.operation {
+			case types.OperationDownscale:
+				if s.NextDownscaleTime == nil || next.Before(s.NextDownscaleTime) {
+					s.NextDownscaleTime = &next
+				}
+			case types.OperationUpscale:
+				if s.NextUpscaleTime == nil || next.Before(s.NextUpscaleTime) {
+					s.NextUpscaleTime = &next
+				}
+			}
+		}
+	}
+}
+
+func (dc *Downscaler) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	meta.SetStatusCondition(&dc.status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: dc.app.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	})
+}
+
+func (dc *Downscaler) setScheduleConditions(scheduleErrors []error) {
+	if len(scheduleErrors) > 0 {
+		dc.setCondition(downscalergov1alpha1.ConditionScheduleValid, metav1.ConditionFalse, reasonSchedulingFailed, errors.Join(scheduleErrors...).Error())
+	} else {
+		dc.setCondition(downscalergov1alpha1.ConditionScheduleValid, metav1.ConditionTrue, reasonScheduled, "all rules were scheduled")
+	}
+	dc.setCondition(downscalergov1alpha1.ConditionReady, metav1.ConditionTrue, reasonScheduled, "cron scheduler is running")
+}
+
+func (dc *Downscaler) publishStatus() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if err := dc.client.PatchDownscalerStatus(dc.app, dc.status); err != nil {
+		dc.log.Error(err, "status", "name", dc.app.Name, "patching status error", err)
+	}
+}
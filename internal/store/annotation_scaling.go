@@ -0,0 +1,357 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	appsv1 "k8s.io/api/apps/v1"
+	v2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotations written on the scaled object by the annotations store.
+const (
+	RecordedReplicasAnnotation    = "kubetime-scaler/recorded-replicas"
+	RecordedMaxReplicasAnnotation = "kubetime-scaler/recorded-max-replicas"
+	RecordedRuleAnnotation        = "kubetime-scaler/recorded-rule"
+	RecordedDownscalerAnnotation  = "kubetime-scaler/recorded-downscaler"
+	RecordedAtAnnotation          = "kubetime-scaler/recorded-at"
+)
+
+var ErrUnsupportedResourceType = errors.New("resource type is not supported by the annotations store")
+
+// annotatedResourceTypes are the built-in resource types the annotations store knows how to fetch.
+// Custom resources declared in scalableResources are fetched as unstructured objects of the kind
+// their scaler registered.
+var annotatedResourceTypes = []types.ResourceType{
+	types.DeploymentObjectResource,
+	types.StatefulSetObjectResource,
+	types.HorizontalPodAutoscalerObjectResource,
+	types.CronJobObjectResource,
+}
+
+// AnnotationScalingOperationStore keeps the scaling operation on the scaled object itself, so the
+// replicas survive restarts of the controller without any database.
+type AnnotationScalingOperationStore struct {
+	client client.Client
+
+	mu    sync.RWMutex
+	kinds map[types.ResourceType]schema.GroupVersionKind
+}
+
+func NewAnnotationScalingOperationStore(c client.Client) *AnnotationScalingOperationStore {
+	return &AnnotationScalingOperationStore{
+		client: c,
+		kinds:  make(map[types.ResourceType]schema.GroupVersionKind),
+	}
+}
+
+// RegisterKind lets the store annotate the custom resources of the resource type.
+func (so *AnnotationScalingOperationStore) RegisterKind(resourceType types.ResourceType, gvk schema.GroupVersionKind) {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	so.kinds[resourceType] = gvk
+}
+
+func (so *AnnotationScalingOperationStore) kind(resourceType types.ResourceType) (schema.GroupVersionKind, bool) {
+	so.mu.RLock()
+	defer so.mu.RUnlock()
+
+	gvk, found := so.kinds[resourceType]
+	return gvk, found
+}
+
+// resourceTypes returns the built-in resource types followed by the registered custom ones.
+func (so *AnnotationScalingOperationStore) resourceTypes() []types.ResourceType {
+	so.mu.RLock()
+	defer so.mu.RUnlock()
+
+	resourceTypes := slices.Clone(annotatedResourceTypes)
+	for resourceType := range so.kinds {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	slices.Sort(resourceTypes[len(annotatedResourceTypes):])
+	return resourceTypes
+}
+
+func (so *AnnotationScalingOperationStore) newAnnotatedObject(resourceType string) (client.Object, error) {
+	switch types.ResourceType(resourceType) {
+	case types.DeploymentObjectResource:
+		return &appsv1.Deployment{}, nil
+	case types.StatefulSetObjectResource:
+		return &appsv1.StatefulSet{}, nil
+	case types.HorizontalPodAutoscalerObjectResource:
+		return &v2.HorizontalPodAutoscaler{}, nil
+	case types.CronJobObjectResource:
+		return &batchv1.CronJob{}, nil
+	}
+
+	gvk, found := so.kind(types.ResourceType(resourceType))
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedResourceType, resourceType)
+	}
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	return object, nil
+}
+
+func (so *AnnotationScalingOperationStore) newAnnotatedObjectList(resourceType types.ResourceType) (client.ObjectList, error) {
+	switch resourceType {
+	case types.DeploymentObjectResource:
+		return &appsv1.DeploymentList{}, nil
+	case types.StatefulSetObjectResource:
+		return &appsv1.StatefulSetList{}, nil
+	case types.HorizontalPodAutoscalerObjectResource:
+		return &v2.HorizontalPodAutoscalerList{}, nil
+	case types.CronJobObjectResource:
+		return &batchv1.CronJobList{}, nil
+	}
+
+	gvk, found := so.kind(resourceType)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedResourceType, resourceType)
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return list, nil
+}
+
+// Bootstrap has nothing to create, the records live on the objects.
+func (so *AnnotationScalingOperationStore) Bootstrap(ctx context.Context) error {
+	return nil
+}
+
+func (so *AnnotationScalingOperationStore) getObject(ctx context.Context, scalingObject *ScalingOperation) (client.Object, error) {
+	object, err := so.newAnnotatedObject(scalingObject.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := so.client.Get(ctx, k8stypes.NamespacedName{
+		Name:      scalingObject.ResourceName,
+		Namespace: scalingObject.NamespaceName,
+	}, object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// Get returns sql.ErrNoRows when the object carries no record of the downscaler, like the sql stores do.
+func (so *AnnotationScalingOperationStore) Get(ctx context.Context, scalingObject *ScalingOperation) error {
+	object, err := so.getObject(ctx, scalingObject)
+	if err != nil {
+		return err
+	}
+
+	if !recordedBy(object.GetAnnotations(), scalingObject.DownscalerName) {
+		return sql.ErrNoRows
+	}
+
+	return readRecord(object.GetAnnotations(), scalingObject)
+}
+
+func (so *AnnotationScalingOperationStore) Insert(ctx context.Context, scalingObject *ScalingOperation) error {
+	object, err := so.getObject(ctx, scalingObject)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := so.writeRecord(ctx, object, scalingObject, now); err != nil {
+		return err
+	}
+
+	scalingObject.CreatedAt = now
+	return nil
+}
+
+// Update returns sql.ErrNoRows when the object carries no record yet, so the caller inserts it.
+func (so *AnnotationScalingOperationStore) Update(ctx context.Context, scalingObject *ScalingOperation) error {
+	object, err := so.getObject(ctx, scalingObject)
+	if err != nil {
+		return err
+	}
+
+	if _, found := object.GetAnnotations()[RecordedReplicasAnnotation]; !found {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := so.writeRecord(ctx, object, scalingObject, now); err != nil {
+		return err
+	}
+
+	scalingObject.UpdatedAt = now
+	return nil
+}
+
+// Upsert overwrites the record of the object, the annotations are written by a single patch.
+func (so *AnnotationScalingOperationStore) Upsert(ctx context.Context, scalingObject *ScalingOperation) error {
+	object, err := so.getObject(ctx, scalingObject)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := so.writeRecord(ctx, object, scalingObject, now); err != nil {
+		return err
+	}
+
+	scalingObject.CreatedAt = now
+	scalingObject.UpdatedAt = now
+	return nil
+}
+
+func (so *AnnotationScalingOperationStore) List(ctx context.Context, filter ScalingOperationFilter) ([]ScalingOperation, error) {
+	var scalingObjects []ScalingOperation
+
+	for _, resourceType := range so.resourceTypes() {
+		list, err := so.newAnnotatedObjectList(resourceType)
+		if err != nil {
+			return nil, err
+		}
+		if err := so.client.List(ctx, list, client.InNamespace(filter.NamespaceName)); err != nil {
+			return nil, err
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			object, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+
+			if filter.DownscalerName != "" && !recordedBy(object.GetAnnotations(), filter.DownscalerName) {
+				continue
+			}
+
+			scalingObject := ScalingOperation{
+				NamespaceName: object.GetNamespace(),
+				ResourceName:  object.GetName(),
+				ResourceType:  resourceType.String(),
+			}
+			if err := readRecord(object.GetAnnotations(), &scalingObject); err != nil {
+				continue
+			}
+			scalingObjects = append(scalingObjects, scalingObject)
+		}
+	}
+
+	return scalingObjects, nil
+}
+
+func (so *AnnotationScalingOperationStore) Delete(ctx context.Context, scalingObject *ScalingOperation) error {
+	object, err := so.getObject(ctx, scalingObject)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	return so.patchAnnotations(ctx, object, map[string]*string{
+		RecordedReplicasAnnotation:    nil,
+		RecordedMaxReplicasAnnotation: nil,
+		RecordedRuleAnnotation:        nil,
+		RecordedDownscalerAnnotation:  nil,
+		RecordedAtAnnotation:          nil,
+	})
+}
+
+func (so *AnnotationScalingOperationStore) writeRecord(ctx context.Context, object client.Object, scalingObject *ScalingOperation, recordedAt string) error {
+	replicas := strconv.Itoa(scalingObject.Replicas)
+	annotations := map[string]*string{
+		RecordedReplicasAnnotation:    &replicas,
+		RecordedMaxReplicasAnnotation: nil,
+		RecordedRuleAnnotation:        &scalingObject.RuleNameDescription,
+		RecordedDownscalerAnnotation:  nil,
+		RecordedAtAnnotation:          &recordedAt,
+	}
+
+	if scalingObject.DownscalerName != "" {
+		annotations[RecordedDownscalerAnnotation] = &scalingObject.DownscalerName
+	}
+
+	if scalingObject.MaxReplicas != 0 {
+		maxReplicas := strconv.Itoa(scalingObject.MaxReplicas)
+		annotations[RecordedMaxReplicasAnnotation] = &maxReplicas
+	}
+
+	return so.patchAnnotations(ctx, object, annotations)
+}
+
+// patchAnnotations sends a merge patch with only the annotations, nil values remove the key.
+func (so *AnnotationScalingOperationStore) patchAnnotations(ctx context.Context, object client.Object, annotations map[string]*string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+
+	return so.client.Patch(ctx, object, client.RawPatch(k8stypes.MergePatchType, patch))
+}
+
+func readRecord(annotations map[string]string, scalingObject *ScalingOperation) error {
+	value, found := annotations[RecordedReplicasAnnotation]
+	if !found {
+		return sql.ErrNoRows
+	}
+
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s annotation %q: %v", RecordedReplicasAnnotation, value, err)
+	}
+
+	maxReplicas := 0
+	if value, found := annotations[RecordedMaxReplicasAnnotation]; found {
+		if maxReplicas, err = strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid %s annotation %q: %v", RecordedMaxReplicasAnnotation, value, err)
+		}
+	}
+
+	scalingObject.Replicas = replicas
+	scalingObject.MaxReplicas = maxReplicas
+	scalingObject.RuleNameDescription = annotations[RecordedRuleAnnotation]
+	scalingObject.DownscalerName = annotations[RecordedDownscalerAnnotation]
+	scalingObject.CreatedAt = annotations[RecordedAtAnnotation]
+	scalingObject.UpdatedAt = annotations[RecordedAtAnnotation]
+
+	return nil
+}
+
+// recordedBy reports whether the record on the object belongs to the downscaler. Records without
+// a downscaler, or a lookup without one, match every downscaler.
+func recordedBy(annotations map[string]string, downscalerName string) bool {
+	recorded := annotations[RecordedDownscalerAnnotation]
+	return downscalerName == "" || recorded == "" || recorded == downscalerName
+}
+
+// RecordEvent drops the event, the history needs one of the sql stores.
+func (so *AnnotationScalingOperationStore) RecordEvent(ctx context.Context, event *ScalingEvent) error {
+	return nil
+}
+
+func (so *AnnotationScalingOperationStore) QueryEvents(ctx context.Context, filter ScalingEventFilter) ([]ScalingEvent, error) {
+	return nil, ErrEventsNotSupported
+}
+
+func (so *AnnotationScalingOperationStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	return 0, ErrEventsNotSupported
+}
@@ -0,0 +1,164 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAnnotationScalingOperationLifecycle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = v2.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-name", Namespace: "test-namespace"}},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-name", Namespace: "other-namespace"}},
+			&v2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "test-name", Namespace: "test-namespace"}},
+		).
+		Build()
+
+	p := store.NewAnnotationScalingOperationStore(fakeClient)
+	ctx := context.Background()
+
+	t.Run("Bootstrap", func(t *testing.T) {
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("bootstrap annotations store should not return an error: %v", err)
+		}
+	})
+
+	scalingObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule",
+		ResourceName:        "test-name",
+		ResourceType:        "deployments",
+		Replicas:            5,
+	}
+
+	t.Run("UpdateWithoutRecord", func(t *testing.T) {
+		assert.ErrorIs(t, p.Update(ctx, scalingObject), sql.ErrNoRows)
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		if err := p.Insert(ctx, scalingObject); err != nil {
+			t.Fatalf("insert annotations operation failed: %v", err)
+		}
+		assert.NotEmpty(t, scalingObject.CreatedAt)
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, k8stypes.NamespacedName{Name: "test-name", Namespace: "test-namespace"}, deployment); err != nil {
+			t.Fatalf("error getting annotated deployment: %v", err)
+		}
+		assert.Equal(t, "5", deployment.Annotations[store.RecordedReplicasAnnotation])
+		assert.Equal(t, "test-rule", deployment.Annotations[store.RecordedRuleAnnotation])
+	})
+
+	updateObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule-updated",
+		ResourceName:        "test-name",
+		ResourceType:        "deployments",
+		Replicas:            10,
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		if err := p.Update(ctx, updateObject); err != nil {
+			t.Fatalf("update annotations operation failed: %v", err)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		getObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get updated object annotations error: %v", err)
+		}
+		assert.Equal(t, updateObject.Replicas, getObject.Replicas)
+		assert.Equal(t, updateObject.RuleNameDescription, getObject.RuleNameDescription)
+		assert.Equal(t, updateObject.UpdatedAt, getObject.UpdatedAt)
+
+		missingObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "other-namespace", ResourceType: "deployments"}
+		assert.ErrorIs(t, p.Get(ctx, missingObject), sql.ErrNoRows)
+	})
+
+	t.Run("SameNameDifferentResourceType", func(t *testing.T) {
+		hpaObject := &store.ScalingOperation{
+			NamespaceName:       "test-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "hpa",
+			Replicas:            2,
+			MaxReplicas:         8,
+		}
+		if err := p.Insert(ctx, hpaObject); err != nil {
+			t.Fatalf("insert annotations operation failed: %v", err)
+		}
+
+		getHpaObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "hpa"}
+		if err := p.Get(ctx, getHpaObject); err != nil {
+			t.Fatalf("get hpa object annotations error: %v", err)
+		}
+		assert.Equal(t, 2, getHpaObject.Replicas)
+		assert.Equal(t, 8, getHpaObject.MaxReplicas)
+
+		if err := p.Delete(ctx, hpaObject); err != nil {
+			t.Fatalf("delete annotations operation failed: %v", err)
+		}
+	})
+
+	t.Run("UnsupportedResourceType", func(t *testing.T) {
+		customObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "rollouts"}
+		assert.ErrorIs(t, p.Get(ctx, customObject), store.ErrUnsupportedResourceType)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		otherNamespaceObject := &store.ScalingOperation{
+			NamespaceName:       "other-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "deployments",
+			Replicas:            3,
+		}
+		if err := p.Insert(ctx, otherNamespaceObject); err != nil {
+			t.Fatalf("insert annotations operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list annotations operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 1)
+		assert.Equal(t, "test-namespace", scalingObjects[0].NamespaceName)
+		assert.Equal(t, updateObject.Replicas, scalingObjects[0].Replicas)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{})
+		if err != nil {
+			t.Fatalf("list annotations operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := p.Delete(ctx, updateObject); err != nil {
+			t.Fatalf("delete annotations operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list annotations operations failed: %v", err)
+		}
+		assert.Empty(t, scalingObjects)
+	})
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	configMapNamePrefix = "kubetime-scaler-state-"
+
+	// ConfigMapStateLabel marks the configmaps of the store and holds the namespace their records belong to.
+	ConfigMapStateLabel = "kubetime-scaler/state-namespace"
+)
+
+// ConfigMapScalingOperationStore keeps the records of every scaled namespace in its own configmap,
+// created in the controller namespace. Each record is a json encoded ScalingOperation keyed by
+// downscaler, resource type and name, and every write is retried on conflict, relying on the resourceVersion
+// of the configmap for optimistic concurrency. The configmaps are read through reader, the api reader
+// of the manager, so no informer caching every configmap of the cluster is started.
+type ConfigMapScalingOperationStore struct {
+	client    client.Client
+	reader    client.Reader
+	namespace string
+}
+
+func NewConfigMapScalingOperationStore(c client.Client, reader client.Reader, namespace string) *ConfigMapScalingOperationStore {
+	return &ConfigMapScalingOperationStore{client: c, reader: reader, namespace: namespace}
+}
+
+func configMapName(namespace string) string {
+	return configMapNamePrefix + namespace
+}
+
+// configMapKey joins the downscaler, with its "/" replaced as configmap keys do not allow it, the
+// resource type and the resource name.
+func configMapKey(scalingObject *ScalingOperation) string {
+	key := scalingObject.ResourceType + "." + scalingObject.ResourceName
+	if scalingObject.DownscalerName == "" {
+		return key
+	}
+
+	return strings.ReplaceAll(scalingObject.DownscalerName, "/", "_") + "." + key
+}
+
+// Bootstrap has nothing to create, the configmaps are created on the first insert of each namespace.
+func (so *ConfigMapScalingOperationStore) Bootstrap(ctx context.Context) error {
+	return nil
+}
+
+func (so *ConfigMapScalingOperationStore) getConfigMap(ctx context.Context, namespace string) (*corev1.ConfigMap, error) {
+	var configMap corev1.ConfigMap
+	if err := so.reader.Get(ctx, k8stypes.NamespacedName{
+		Name:      configMapName(namespace),
+		Namespace: so.namespace,
+	}, &configMap); err != nil {
+		return nil, err
+	}
+
+	return &configMap, nil
+}
+
+// Get returns sql.ErrNoRows when there is no record, like the sql stores do.
+func (so *ConfigMapScalingOperationStore) Get(ctx context.Context, scalingObject *ScalingOperation) error {
+	configMap, err := so.getConfigMap(ctx, scalingObject.NamespaceName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+
+	value, found := configMap.Data[configMapKey(scalingObject)]
+	if !found {
+		return sql.ErrNoRows
+	}
+
+	return json.Unmarshal([]byte(value), scalingObject)
+}
+
+func (so *ConfigMapScalingOperationStore) Insert(ctx context.Context, scalingObject *ScalingOperation) error {
+	scalingObject.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	scalingObject.UpdatedAt = scalingObject.CreatedAt
+
+	return so.mutate(ctx, scalingObject.NamespaceName, true, func(data map[string]string) error {
+		return writeConfigMapRecord(data, scalingObject)
+	})
+}
+
+// Update returns sql.ErrNoRows when there is no record yet, so the caller inserts it.
+func (so *ConfigMapScalingOperationStore) Update(ctx context.Context, scalingObject *ScalingOperation) error {
+	err := so.mutate(ctx, scalingObject.NamespaceName, false, func(data map[string]string) error {
+		value, found := data[configMapKey(scalingObject)]
+		if !found {
+			return sql.ErrNoRows
+		}
+
+		var previous ScalingOperation
+		if err := json.Unmarshal([]byte(value), &previous); err != nil {
+			return err
+		}
+
+		scalingObject.CreatedAt = previous.CreatedAt
+		scalingObject.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+		return writeConfigMapRecord(data, scalingObject)
+	})
+	if apierrors.IsNotFound(err) {
+		return sql.ErrNoRows
+	}
+
+	return err
+}
+
+func (so *ConfigMapScalingOperationStore) Upsert(ctx context.Context, scalingObject *ScalingOperation) error {
+	return so.mutate(ctx, scalingObject.NamespaceName, true, func(data map[string]string) error {
+		now := time.Now().UTC().Format(time.RFC3339)
+		scalingObject.CreatedAt, scalingObject.UpdatedAt = now, now
+
+		if value, found := data[configMapKey(scalingObject)]; found {
+			var previous ScalingOperation
+			if err := json.Unmarshal([]byte(value), &previous); err != nil {
+				return err
+			}
+			scalingObject.CreatedAt = previous.CreatedAt
+		}
+
+		return writeConfigMapRecord(data, scalingObject)
+	})
+}
+
+func (so *ConfigMapScalingOperationStore) List(ctx context.Context, filter ScalingOperationFilter) ([]ScalingOperation, error) {
+	var configMaps []corev1.ConfigMap
+
+	if filter.NamespaceName != "" {
+		configMap, err := so.getConfigMap(ctx, filter.NamespaceName)
+		if err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		configMaps = append(configMaps, *configMap)
+	} else {
+		var configMapList corev1.ConfigMapList
+		if err := so.reader.List(ctx, &configMapList,
+			client.InNamespace(so.namespace),
+			client.HasLabels{ConfigMapStateLabel},
+		); err != nil {
+			return nil, err
+		}
+		configMaps = configMapList.Items
+	}
+
+	var scalingObjects []ScalingOperation
+	for _, configMap := range configMaps {
+		keys := make([]string, 0, len(configMap.Data))
+		for key := range configMap.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var scalingObject ScalingOperation
+			if err := json.Unmarshal([]byte(configMap.Data[key]), &scalingObject); err != nil {
+				return nil, fmt.Errorf("decoding %s/%s key %s: %v", configMap.Namespace, configMap.Name, key, err)
+			}
+			if filter.DownscalerName != "" && scalingObject.DownscalerName != filter.DownscalerName {
+				continue
+			}
+			scalingObjects = append(scalingObjects, scalingObject)
+		}
+	}
+
+	return scalingObjects, nil
+}
+
+func (so *ConfigMapScalingOperationStore) Delete(ctx context.Context, scalingObject *ScalingOperation) error {
+	err := so.mutate(ctx, scalingObject.NamespaceName, false, func(data map[string]string) error {
+		delete(data, configMapKey(scalingObject))
+		return nil
+	})
+
+	return client.IgnoreNotFound(err)
+}
+
+// mutate applies fn to the data of the configmap of the namespace and writes it back, reading the
+// configmap again whenever the write conflicts with a concurrent one. The configmap is created
+// when missing only if create is set, otherwise the NotFound error is returned.
+func (so *ConfigMapScalingOperationStore) mutate(ctx context.Context, namespace string, create bool, fn func(data map[string]string) error) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		configMap, err := so.getConfigMap(ctx, namespace)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			if !create {
+				return err
+			}
+
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      configMapName(namespace),
+					Namespace: so.namespace,
+					Labels: map[string]string{
+						"app.kubernetes.io/managed-by": "kubetime-scaler",
+						ConfigMapStateLabel:            namespace,
+					},
+				},
+				Data: map[string]string{},
+			}
+			if err := fn(configMap.Data); err != nil {
+				return err
+			}
+			// a concurrent create is reported as AlreadyExists, retried as a conflict.
+			if err := so.client.Create(ctx, configMap); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					return apierrors.NewConflict(corev1.Resource("configmaps"), configMap.Name, err)
+				}
+				return err
+			}
+			return nil
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		if err := fn(configMap.Data); err != nil {
+			return err
+		}
+
+		// the configmap goes away with its last record, the precondition keeps a concurrent insert.
+		if len(configMap.Data) == 0 {
+			err := so.client.Delete(ctx, configMap, client.Preconditions{ResourceVersion: &configMap.ResourceVersion})
+			return client.IgnoreNotFound(err)
+		}
+
+		return so.client.Update(ctx, configMap)
+	})
+}
+
+func writeConfigMapRecord(data map[string]string, scalingObject *ScalingOperation) error {
+	value, err := json.Marshal(scalingObject)
+	if err != nil {
+		return err
+	}
+
+	data[configMapKey(scalingObject)] = string(value)
+	return nil
+}
+
+// RecordEvent drops the event, the history needs one of the sql stores.
+func (so *ConfigMapScalingOperationStore) RecordEvent(ctx context.Context, event *ScalingEvent) error {
+	return nil
+}
+
+func (so *ConfigMapScalingOperationStore) QueryEvents(ctx context.Context, filter ScalingEventFilter) ([]ScalingEvent, error) {
+	return nil, ErrEventsNotSupported
+}
+
+func (so *ConfigMapScalingOperationStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	return 0, ErrEventsNotSupported
+}
@@ -0,0 +1,190 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const controllerNamespace = "kubetime-scaler"
+
+func TestConfigMapScalingOperationLifecycle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	p := store.NewConfigMapScalingOperationStore(fakeClient, fakeClient, controllerNamespace)
+	ctx := context.Background()
+
+	t.Run("Bootstrap", func(t *testing.T) {
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("bootstrap configmap store should not return an error: %v", err)
+		}
+	})
+
+	scalingObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule",
+		ResourceName:        "test-name",
+		ResourceType:        "deployments",
+		Replicas:            5,
+	}
+
+	t.Run("UpdateWithoutRecord", func(t *testing.T) {
+		assert.ErrorIs(t, p.Update(ctx, scalingObject), sql.ErrNoRows)
+		assert.ErrorIs(t, p.Get(ctx, &store.ScalingOperation{
+			NamespaceName: "test-namespace",
+			ResourceName:  "test-name",
+			ResourceType:  "deployments",
+		}), sql.ErrNoRows)
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		if err := p.Insert(ctx, scalingObject); err != nil {
+			t.Fatalf("insert configmap operation failed: %v", err)
+		}
+		assert.NotEmpty(t, scalingObject.CreatedAt)
+
+		var configMap corev1.ConfigMap
+		err := fakeClient.Get(ctx, k8stypes.NamespacedName{Name: "kubetime-scaler-state-test-namespace", Namespace: controllerNamespace}, &configMap)
+		assert.NoError(t, err)
+		assert.Equal(t, "test-namespace", configMap.Labels[store.ConfigMapStateLabel])
+		assert.Contains(t, configMap.Data, "deployments.test-name")
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		retrieved := &store.ScalingOperation{
+			NamespaceName: "test-namespace",
+			ResourceName:  "test-name",
+			ResourceType:  "deployments",
+		}
+
+		assert.NoError(t, p.Get(ctx, retrieved))
+		assert.Equal(t, 5, retrieved.Replicas)
+		assert.Equal(t, "test-rule", retrieved.RuleNameDescription)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		scalingObject.Replicas = 10
+		assert.NoError(t, p.Update(ctx, scalingObject))
+
+		updated := &store.ScalingOperation{
+			NamespaceName: "test-namespace",
+			ResourceName:  "test-name",
+			ResourceType:  "deployments",
+		}
+		assert.NoError(t, p.Get(ctx, updated))
+		assert.Equal(t, 10, updated.Replicas)
+		assert.NotEmpty(t, updated.UpdatedAt)
+	})
+
+	t.Run("UpsertPerDownscaler", func(t *testing.T) {
+		upsertObject := &store.ScalingOperation{
+			DownscalerName: "kubetime-scaler/other",
+			NamespaceName:  "test-namespace",
+			ResourceName:   "test-name",
+			ResourceType:   "deployments",
+			Replicas:       3,
+		}
+		assert.NoError(t, p.Upsert(ctx, upsertObject))
+		upsertObject.Replicas = 4
+		assert.NoError(t, p.Upsert(ctx, upsertObject))
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{DownscalerName: "kubetime-scaler/other"})
+		assert.NoError(t, err)
+		assert.Len(t, scalingObjects, 1)
+		assert.Equal(t, 4, scalingObjects[0].Replicas)
+
+		assert.NoError(t, p.Delete(ctx, upsertObject))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		other := &store.ScalingOperation{
+			NamespaceName:       "other-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "statefulset",
+			Replicas:            2,
+		}
+		assert.NoError(t, p.Insert(ctx, other))
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		assert.NoError(t, err)
+		assert.Len(t, scalingObjects, 1)
+		assert.Equal(t, 10, scalingObjects[0].Replicas)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, scalingObjects, 2)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{NamespaceName: "missing-namespace"})
+		assert.NoError(t, err)
+		assert.Empty(t, scalingObjects)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, p.Delete(ctx, scalingObject))
+		assert.ErrorIs(t, p.Get(ctx, scalingObject), sql.ErrNoRows)
+
+		var configMap corev1.ConfigMap
+		err := fakeClient.Get(ctx, k8stypes.NamespacedName{Name: "kubetime-scaler-state-test-namespace", Namespace: controllerNamespace}, &configMap)
+		assert.True(t, apierrors.IsNotFound(err), "configmap without records should be deleted")
+
+		assert.NoError(t, p.Delete(ctx, scalingObject))
+	})
+}
+
+func TestConfigMapScalingOperationRetriesOnConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	conflicts := 0
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				// a concurrent writer records another workload between the read and the write.
+				if conflicts == 0 {
+					conflicts++
+					configMap := obj.DeepCopyObject().(*corev1.ConfigMap)
+					delete(configMap.Data, "deployments.second")
+					configMap.Data["statefulset.concurrent"] = `{"resource_name":"concurrent","resource_type":"statefulset","replicas":3}`
+					if err := c.Update(ctx, configMap); err != nil {
+						return err
+					}
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	p := store.NewConfigMapScalingOperationStore(fakeClient, fakeClient, controllerNamespace)
+	ctx := context.Background()
+
+	first := &store.ScalingOperation{NamespaceName: "test-namespace", ResourceName: "first", ResourceType: "deployments", Replicas: 1}
+	second := &store.ScalingOperation{NamespaceName: "test-namespace", ResourceName: "second", ResourceType: "deployments", Replicas: 2}
+
+	assert.NoError(t, p.Insert(ctx, first))
+	assert.NoError(t, p.Insert(ctx, second))
+	assert.Equal(t, 1, conflicts)
+
+	scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+	assert.NoError(t, err)
+
+	var names []string
+	for _, scalingObject := range scalingObjects {
+		names = append(names, scalingObject.ResourceName)
+	}
+	assert.ElementsMatch(t, []string{"first", "concurrent", "second"}, names)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	EventOutcomeSucceeded = "succeeded"
+	EventOutcomeFailed    = "failed"
+
+	// timestampLayout matches current_timestamp of sqlite and the datetime text of mysql, so the
+	// values written by the stores compare and sort by time.
+	timestampLayout = "2006-01-02 15:04:05"
+)
+
+var ErrEventsNotSupported = errors.New("scaling events are only kept by the sqlite, postgres, mysql and memory stores")
+
+// ScalingEvent is one row of the append-only scaling history, written every time an object is
+// patched whatever the outcome. Replicas hold the suspend value for cronjobs and minReplicas for hpas.
+type ScalingEvent struct {
+	ID             int    `json:"id"`
+	DownscalerName string `json:"downscaler_name"`
+	RuleName       string `json:"rule_name"`
+	NamespaceName  string `json:"namespace_name"`
+	ResourceType   string `json:"resource_type"`
+	ResourceName   string `json:"resource_name"`
+	Operation      string `json:"operation"`
+	ReplicasBefore int    `json:"replicas_before"`
+	ReplicasAfter  int    `json:"replicas_after"`
+	Outcome        string `json:"outcome"`
+	Error          string `json:"error,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// ScalingEventFilter narrows QueryEvents results. Empty fields match every event, zero times leave
+// the range open and a zero Limit returns every matching event.
+type ScalingEventFilter struct {
+	DownscalerName string
+	NamespaceName  string
+	ResourceName   string
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+}
+
+func NewScalingEvent(scalingObject ScalingOperation, operation string, before, after int, scalingErr error) *ScalingEvent {
+	event := &ScalingEvent{
+		DownscalerName: scalingObject.DownscalerName,
+		RuleName:       scalingObject.RuleNameDescription,
+		NamespaceName:  scalingObject.NamespaceName,
+		ResourceType:   scalingObject.ResourceType,
+		ResourceName:   scalingObject.ResourceName,
+		Operation:      operation,
+		ReplicasBefore: before,
+		ReplicasAfter:  after,
+		Outcome:        EventOutcomeSucceeded,
+	}
+
+	if scalingErr != nil {
+		event.Outcome = EventOutcomeFailed
+		event.Error = scalingErr.Error()
+	}
+
+	return event
+}
+
+func scanEvents(rows *sql.Rows) ([]ScalingEvent, error) {
+	var events []ScalingEvent
+	for rows.Next() {
+		var event ScalingEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.DownscalerName,
+			&event.RuleName,
+			&event.NamespaceName,
+			&event.ResourceType,
+			&event.ResourceName,
+			&event.Operation,
+			&event.ReplicasBefore,
+			&event.ReplicasAfter,
+			&event.Outcome,
+			&event.Error,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// EventsPurger deletes the scaling events older than the retention on every interval. It is added
+// to the controller manager, so it runs with the manager and stops with it.
+type EventsPurger struct {
+	storer    ScalingOperationStorer
+	log       logr.Logger
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewEventsPurger(log logr.Logger, storer ScalingOperationStorer, retention, interval time.Duration) *EventsPurger {
+	return &EventsPurger{storer: storer, log: log, retention: retention, interval: interval}
+}
+
+func (ep *EventsPurger) Start(ctx context.Context) error {
+	ticker := time.NewTicker(ep.interval)
+	defer ticker.Stop()
+
+	for {
+		ep.Purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ep *EventsPurger) Purge(ctx context.Context) {
+	purged, err := ep.storer.PurgeEvents(ctx, time.Now().Add(-ep.retention))
+	if err != nil {
+		if !errors.Is(err, ErrEventsNotSupported) {
+			ep.log.Error(err, "database", "purging scaling events error", err)
+		}
+		return
+	}
+
+	if purged > 0 {
+		ep.log.Info("database", "purged scaling events", purged, "retention", ep.retention.String())
+	}
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrRecordExists = errors.New("scaling operation record already exists")
+
+type memoryKey struct {
+	downscalerName string
+	namespaceName  string
+	resourceType   string
+	resourceName   string
+}
+
+func newMemoryKey(scalingObject *ScalingOperation) memoryKey {
+	return memoryKey{
+		downscalerName: scalingObject.DownscalerName,
+		namespaceName:  scalingObject.NamespaceName,
+		resourceType:   scalingObject.ResourceType,
+		resourceName:   scalingObject.ResourceName,
+	}
+}
+
+// memoryEvent keeps the creation time next to the event, so the range filters and the purge do
+// not parse CreatedAt back.
+type memoryEvent struct {
+	event     ScalingEvent
+	createdAt time.Time
+}
+
+// MemoryScalingOperationStore keeps the records in a map guarded by a mutex, with the same
+// semantics as the sql stores. The records live as long as the pod does.
+type MemoryScalingOperationStore struct {
+	mu          sync.Mutex
+	records     map[memoryKey]ScalingOperation
+	events      []memoryEvent
+	lastID      int
+	lastEventID int
+}
+
+func NewMemoryScalingOperationStore() *MemoryScalingOperationStore {
+	return &MemoryScalingOperationStore{records: make(map[memoryKey]ScalingOperation)}
+}
+
+// Bootstrap has nothing to create.
+func (so *MemoryScalingOperationStore) Bootstrap(ctx context.Context) error {
+	return nil
+}
+
+// Get prefers the record of the downscaler over one written without a downscaler.
+func (so *MemoryScalingOperationStore) Get(ctx context.Context, scalingObject *ScalingOperation) error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	key := newMemoryKey(scalingObject)
+	record, found := so.records[key]
+	if !found {
+		key.downscalerName = ""
+		if record, found = so.records[key]; !found {
+			return sql.ErrNoRows
+		}
+	}
+
+	*scalingObject = record
+	return nil
+}
+
+// Insert returns ErrRecordExists for a key already recorded, like the unique index of the sql stores.
+func (so *MemoryScalingOperationStore) Insert(ctx context.Context, scalingObject *ScalingOperation) error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	key := newMemoryKey(scalingObject)
+	if _, found := so.records[key]; found {
+		return ErrRecordExists
+	}
+
+	so.lastID++
+	scalingObject.ID = so.lastID
+	scalingObject.CreatedAt = time.Now().UTC().Format(timestampLayout)
+	scalingObject.UpdatedAt = scalingObject.CreatedAt
+
+	so.records[key] = *scalingObject
+	return nil
+}
+
+// Update returns sql.ErrNoRows when there is no record yet.
+func (so *MemoryScalingOperationStore) Update(ctx context.Context, scalingObject *ScalingOperation) error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	key := newMemoryKey(scalingObject)
+	record, found := so.records[key]
+	if !found {
+		return sql.ErrNoRows
+	}
+
+	scalingObject.ID = record.ID
+	scalingObject.CreatedAt = record.CreatedAt
+	scalingObject.UpdatedAt = time.Now().UTC().Format(timestampLayout)
+
+	so.records[key] = *scalingObject
+	return nil
+}
+
+func (so *MemoryScalingOperationStore) Upsert(ctx context.Context, scalingObject *ScalingOperation) error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	key := newMemoryKey(scalingObject)
+	now := time.Now().UTC().Format(timestampLayout)
+
+	if record, found := so.records[key]; found {
+		scalingObject.ID = record.ID
+		scalingObject.CreatedAt = record.CreatedAt
+	} else {
+		so.lastID++
+		scalingObject.ID = so.lastID
+		scalingObject.CreatedAt = now
+	}
+	scalingObject.UpdatedAt = now
+
+	so.records[key] = *scalingObject
+	return nil
+}
+
+func (so *MemoryScalingOperationStore) List(ctx context.Context, filter ScalingOperationFilter) ([]ScalingOperation, error) {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	var scalingObjects []ScalingOperation
+	for key, record := range so.records {
+		if filter.NamespaceName != "" && key.namespaceName != filter.NamespaceName {
+			continue
+		}
+		if filter.DownscalerName != "" && key.downscalerName != "" && key.downscalerName != filter.DownscalerName {
+			continue
+		}
+		scalingObjects = append(scalingObjects, record)
+	}
+
+	sort.Slice(scalingObjects, func(i, j int) bool {
+		return scalingObjects[i].ID < scalingObjects[j].ID
+	})
+
+	return scalingObjects, nil
+}
+
+// Delete removes the record of the downscaler and the one written without a downscaler.
+func (so *MemoryScalingOperationStore) Delete(ctx context.Context, scalingObject *ScalingOperation) error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	key := newMemoryKey(scalingObject)
+	delete(so.records, key)
+
+	key.downscalerName = ""
+	delete(so.records, key)
+
+	return nil
+}
+
+func (so *MemoryScalingOperationStore) RecordEvent(ctx context.Context, event *ScalingEvent) error {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	so.lastEventID++
+	event.ID = so.lastEventID
+	event.CreatedAt = now.Format(timestampLayout)
+
+	so.events = append(so.events, memoryEvent{event: *event, createdAt: now})
+	return nil
+}
+
+func (so *MemoryScalingOperationStore) QueryEvents(ctx context.Context, filter ScalingEventFilter) ([]ScalingEvent, error) {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	var events []ScalingEvent
+	for i := len(so.events) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+
+		event := so.events[i]
+		if filter.DownscalerName != "" && event.event.DownscalerName != filter.DownscalerName ||
+			filter.NamespaceName != "" && event.event.NamespaceName != filter.NamespaceName ||
+			filter.ResourceName != "" && event.event.ResourceName != filter.ResourceName ||
+			!filter.Since.IsZero() && event.createdAt.Before(filter.Since) ||
+			!filter.Until.IsZero() && !event.createdAt.Before(filter.Until) {
+			continue
+		}
+
+		events = append(events, event.event)
+	}
+
+	return events, nil
+}
+
+func (so *MemoryScalingOperationStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+
+	kept := so.events[:0]
+	for _, event := range so.events {
+		if event.createdAt.Before(before) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+
+	purged := int64(len(so.events) - len(kept))
+	so.events = kept
+
+	return purged, nil
+}
@@ -0,0 +1,153 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryScalingOperationLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	p := store.NewMemoryScalingOperationStore()
+
+	scalingObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule",
+		ResourceName:        "test-name",
+		ResourceType:        "test-deployment",
+		Replicas:            5,
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		if err := p.Insert(ctx, scalingObject); err != nil {
+			t.Fatalf("insert memory operation failed: %v", err)
+		}
+		assert.Equal(t, 1, scalingObject.ID)
+		assert.NotEmpty(t, scalingObject.CreatedAt)
+
+		duplicate := *scalingObject
+		assert.ErrorIs(t, p.Insert(ctx, &duplicate), store.ErrRecordExists)
+	})
+
+	updateObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule-updated",
+		ResourceName:        "test-name",
+		ResourceType:        "test-deployment",
+		Replicas:            10,
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		if err := p.Update(ctx, updateObject); err != nil {
+			t.Fatalf("update memory operation failed: %v", err)
+		}
+		assert.Equal(t, scalingObject.ID, updateObject.ID)
+
+		missingObject := &store.ScalingOperation{NamespaceName: "test-namespace", ResourceName: "missing", ResourceType: "test-deployment"}
+		assert.ErrorIs(t, p.Update(ctx, missingObject), sql.ErrNoRows)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		getObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "test-deployment"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get updated object memory error: %v", err)
+		}
+		assert.Equal(t, updateObject.RuleNameDescription, getObject.RuleNameDescription)
+		assert.Equal(t, updateObject.Replicas, getObject.Replicas)
+
+		// records written without a downscaler are matched by every downscaler.
+		getObject = &store.ScalingOperation{DownscalerName: "kubetime-scaler/first", ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "test-deployment"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get legacy object memory error: %v", err)
+		}
+		assert.Equal(t, updateObject.Replicas, getObject.Replicas)
+
+		getObject = &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "hpa"}
+		assert.ErrorIs(t, p.Get(ctx, getObject), sql.ErrNoRows)
+	})
+
+	t.Run("UpsertPerDownscaler", func(t *testing.T) {
+		first := &store.ScalingOperation{
+			DownscalerName:      "kubetime-scaler/first",
+			NamespaceName:       "upsert-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "deployments",
+			Replicas:            2,
+		}
+		second := *first
+		second.DownscalerName = "kubetime-scaler/second"
+		second.Replicas = 7
+
+		assert.NoError(t, p.Upsert(ctx, first))
+		assert.NoError(t, p.Upsert(ctx, &second))
+
+		firstID := first.ID
+		first.Replicas = 3
+		assert.NoError(t, p.Upsert(ctx, first))
+		assert.Equal(t, firstID, first.ID)
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "upsert-namespace"})
+		if err != nil {
+			t.Fatalf("list memory operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{NamespaceName: "upsert-namespace", DownscalerName: "kubetime-scaler/first"})
+		if err != nil {
+			t.Fatalf("list memory operations failed: %v", err)
+		}
+		if assert.Len(t, scalingObjects, 1) {
+			assert.Equal(t, 3, scalingObjects[0].Replicas)
+		}
+
+		assert.NoError(t, p.Delete(ctx, first))
+		assert.NoError(t, p.Delete(ctx, &second))
+	})
+
+	t.Run("ConcurrentUpsert", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for replicas := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, p.Upsert(ctx, &store.ScalingOperation{
+					NamespaceName: "concurrent-namespace",
+					ResourceName:  "test-name",
+					ResourceType:  "deployments",
+					Replicas:      replicas,
+				}))
+			}()
+		}
+		wg.Wait()
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "concurrent-namespace"})
+		if err != nil {
+			t.Fatalf("list memory operations failed: %v", err)
+		}
+		if assert.Len(t, scalingObjects, 1) {
+			assert.NoError(t, p.Delete(ctx, &scalingObjects[0]))
+		}
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		testScalingEvents(t, p)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := p.Delete(ctx, updateObject); err != nil {
+			t.Fatalf("delete memory operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{})
+		if err != nil {
+			t.Fatalf("list memory operations failed: %v", err)
+		}
+		assert.Empty(t, scalingObjects)
+	})
+}
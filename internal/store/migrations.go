@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration is one versioned change of the database schema. Versions are applied in ascending
+// order, each one in its own transaction together with its schema_migrations row, so a failed
+// migration leaves the database at the previous version.
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context, tx *sql.Tx) error
+}
+
+// execStatements returns a migration step running the statements in order.
+func execStatements(statements ...string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrator applies the migrations of a driver. The placeholder is the bind parameter prefix of the
+// driver, "?" for sqlite and "$" for the numbered parameters of postgres.
+type migrator struct {
+	db          *sql.DB
+	migrations  []migration
+	placeholder string
+}
+
+func (m migrator) latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// version returns the highest applied migration, 0 for a database that was never migrated.
+func (m migrator) version(ctx context.Context) (int, error) {
+	var version int
+	err := m.db.QueryRowContext(ctx, `select coalesce(max(version), 0) from schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// migrate creates the schema_migrations table, applies every pending migration and checks that
+// the database is not ahead of this binary, which would mean a newer release already migrated it.
+func (m migrator) migrate(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, `
+		create table if not exists schema_migrations (
+			version integer primary key,
+			description text not null,
+			applied_at timestamp default current_timestamp
+		)
+	`); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %v", err)
+	}
+
+	current, err := m.version(ctx)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %v", err)
+	}
+
+	if current > m.latest() {
+		return fmt.Errorf("database schema version %d is newer than the latest supported version %d", current, m.latest())
+	}
+
+	for _, migration := range m.migrations {
+		if migration.version <= current {
+			continue
+		}
+
+		if err := m.apply(ctx, migration); err != nil {
+			return fmt.Errorf("migration %d (%s): %v", migration.version, migration.description, err)
+		}
+	}
+
+	return nil
+}
+
+func (m migrator) apply(ctx context.Context, migration migration) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := migration.up(ctx, tx); err != nil {
+		return err
+	}
+
+	// the primary key makes a concurrent run of the same migration fail instead of applying it twice.
+	if _, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`insert into schema_migrations (version, description) values (%s, %s)`, m.bind(1), m.bind(2)),
+		migration.version,
+		migration.description,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m migrator) bind(position int) string {
+	if m.placeholder == "$" {
+		return fmt.Sprintf("$%d", position)
+	}
+	return m.placeholder
+}
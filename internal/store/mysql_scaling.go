@@ -0,0 +1,402 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// mysqlMigrations must only be appended to, the versions already released are applied on the
+// existing databases and are never run again. MySQL commits every ddl statement on its own, so
+// a failed migration may leave part of its statements applied, they are written to be rerun.
+var mysqlMigrations = []migration{
+	{
+		version:     1,
+		description: "create scaling_operations table",
+		up: execStatements(
+			`create table if not exists scaling_operations (
+				id integer primary key auto_increment,
+				downscaler_name varchar(320) not null default '',
+				namespace_name varchar(63) not null,
+				rule_name_description text,
+				resource_name varchar(253) not null,
+				resource_type varchar(50) not null default '',
+				replicas integer not null,
+				max_replicas integer,
+				created_at datetime default current_timestamp,
+				updated_at datetime default current_timestamp,
+				unique key scaling_operations_key (downscaler_name, namespace_name, resource_type, resource_name)
+			)`,
+		),
+	},
+	{
+		version:     2,
+		description: "create scaling_events history table",
+		up: execStatements(
+			`create table if not exists scaling_events (
+				id bigint primary key auto_increment,
+				downscaler_name varchar(320) not null default '',
+				rule_name text not null,
+				namespace_name varchar(63) not null,
+				resource_type varchar(50) not null,
+				resource_name varchar(253) not null,
+				operation varchar(20) not null,
+				replicas_before integer not null,
+				replicas_after integer not null,
+				outcome varchar(20) not null,
+				error text not null,
+				created_at datetime not null default current_timestamp,
+				key scaling_events_created_at (created_at),
+				key scaling_events_namespace (namespace_name, created_at)
+			)`,
+		),
+	},
+}
+
+// MysqlScalingOperationStore works with MySQL and MariaDB. Neither has "returning" in every
+// supported version, so the ids come from LastInsertId and the timestamps are set by the store.
+type MysqlScalingOperationStore struct {
+	db *sql.DB
+}
+
+func NewMysqlScalingOperationStore(db *sql.DB) *MysqlScalingOperationStore {
+	return &MysqlScalingOperationStore{db: db}
+}
+
+// Bootstrap applies the pending schema migrations.
+func (so *MysqlScalingOperationStore) Bootstrap(ctx context.Context) error {
+	return migrator{db: so.db, migrations: mysqlMigrations, placeholder: "?"}.migrate(ctx)
+}
+
+// Ping reports whether the database answers, it backs the DatabaseAvailable condition.
+func (so *MysqlScalingOperationStore) Ping(ctx context.Context) error {
+	return so.db.PingContext(ctx)
+}
+
+// Get prefers the record of the downscaler over one written before the downscaler was part of the key.
+func (so *MysqlScalingOperationStore) Get(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		select
+		 id, downscaler_name, coalesce(rule_name_description, ''), resource_type,
+		 replicas, coalesce(max_replicas, 0), created_at, updated_at
+		 from scaling_operations
+		 where resource_name = ? and namespace_name = ? and resource_type = ? and downscaler_name in (?, '')
+		 order by downscaler_name desc
+		 limit 1
+	`
+
+	return so.db.QueryRowContext(
+		ctx,
+		query,
+		scalingObject.ResourceName,
+		scalingObject.NamespaceName,
+		scalingObject.ResourceType,
+		scalingObject.DownscalerName,
+	).Scan(
+		&scalingObject.ID,
+		&scalingObject.DownscalerName,
+		&scalingObject.RuleNameDescription,
+		&scalingObject.ResourceType,
+		&scalingObject.Replicas,
+		&scalingObject.MaxReplicas,
+		&scalingObject.CreatedAt,
+		&scalingObject.UpdatedAt,
+	)
+}
+
+func (so *MysqlScalingOperationStore) Insert(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		insert into scaling_operations
+		(downscaler_name, namespace_name, rule_name_description, resource_name, resource_type, replicas, max_replicas, created_at, updated_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now().UTC().Format(timestampLayout)
+
+	result, err := so.db.ExecContext(
+		ctx,
+		query,
+		scalingObject.DownscalerName,
+		scalingObject.NamespaceName,
+		scalingObject.RuleNameDescription,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+		scalingObject.Replicas,
+		scalingObject.MaxReplicas,
+		now,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	scalingObject.ID = int(id)
+	scalingObject.CreatedAt = now
+	return nil
+}
+
+// Update returns sql.ErrNoRows when there is no record yet. The id is read first because MySQL
+// reports the changed rows only, an update writing the same values would look like a missing record.
+func (so *MysqlScalingOperationStore) Update(ctx context.Context, scalingObject *ScalingOperation) error {
+	if err := so.db.QueryRowContext(
+		ctx,
+		`select id from scaling_operations
+		 where downscaler_name = ? and namespace_name = ? and resource_name = ? and resource_type = ?`,
+		scalingObject.DownscalerName,
+		scalingObject.NamespaceName,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+	).Scan(&scalingObject.ID); err != nil {
+		return err
+	}
+
+	query := `
+		update scaling_operations
+		set replicas = ?, rule_name_description = ?, max_replicas = ?, updated_at = ?
+		where id = ?
+	`
+
+	now := time.Now().UTC().Format(timestampLayout)
+
+	if _, err := so.db.ExecContext(
+		ctx,
+		query,
+		scalingObject.Replicas,
+		scalingObject.RuleNameDescription,
+		scalingObject.MaxReplicas,
+		now,
+		scalingObject.ID,
+	); err != nil {
+		return err
+	}
+
+	scalingObject.UpdatedAt = now
+	return nil
+}
+
+// Upsert relies on the unique key of the record. last_insert_id(id) makes LastInsertId return the
+// id of the existing row when it is updated instead of inserted.
+func (so *MysqlScalingOperationStore) Upsert(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		insert into scaling_operations
+		(downscaler_name, namespace_name, rule_name_description, resource_name, resource_type, replicas, max_replicas, created_at, updated_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		on duplicate key update
+		 id = last_insert_id(id),
+		 replicas = values(replicas),
+		 rule_name_description = values(rule_name_description),
+		 max_replicas = values(max_replicas),
+		 updated_at = values(updated_at)
+	`
+
+	now := time.Now().UTC().Format(timestampLayout)
+
+	result, err := so.db.ExecContext(
+		ctx,
+		query,
+		scalingObject.DownscalerName,
+		scalingObject.NamespaceName,
+		scalingObject.RuleNameDescription,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+		scalingObject.Replicas,
+		scalingObject.MaxReplicas,
+		now,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	scalingObject.ID = int(id)
+	scalingObject.UpdatedAt = now
+
+	return so.db.QueryRowContext(
+		ctx,
+		`select created_at from scaling_operations where id = ?`,
+		scalingObject.ID,
+	).Scan(
+		&scalingObject.CreatedAt,
+	)
+}
+
+func (so *MysqlScalingOperationStore) List(ctx context.Context, filter ScalingOperationFilter) ([]ScalingOperation, error) {
+	query := `
+		select
+		 id, downscaler_name, namespace_name, coalesce(rule_name_description, ''), resource_name,
+		 resource_type, replicas, coalesce(max_replicas, 0), created_at, updated_at
+		 from scaling_operations
+		 where (? = '' or namespace_name = ?) and (? = '' or downscaler_name in (?, ''))
+		 order by id
+	`
+
+	rows, err := so.db.QueryContext(
+		ctx,
+		query,
+		filter.NamespaceName,
+		filter.NamespaceName,
+		filter.DownscalerName,
+		filter.DownscalerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scalingObjects []ScalingOperation
+	for rows.Next() {
+		var scalingObject ScalingOperation
+		if err := rows.Scan(
+			&scalingObject.ID,
+			&scalingObject.DownscalerName,
+			&scalingObject.NamespaceName,
+			&scalingObject.RuleNameDescription,
+			&scalingObject.ResourceName,
+			&scalingObject.ResourceType,
+			&scalingObject.Replicas,
+			&scalingObject.MaxReplicas,
+			&scalingObject.CreatedAt,
+			&scalingObject.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		scalingObjects = append(scalingObjects, scalingObject)
+	}
+
+	return scalingObjects, rows.Err()
+}
+
+func (so *MysqlScalingOperationStore) Delete(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		delete from scaling_operations
+		where namespace_name = ? and resource_name = ? and resource_type = ? and downscaler_name in (?, '')
+	`
+
+	_, err := so.db.ExecContext(
+		ctx,
+		query,
+		scalingObject.NamespaceName,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+		scalingObject.DownscalerName,
+	)
+
+	return err
+}
+
+func (so *MysqlScalingOperationStore) RecordEvent(ctx context.Context, event *ScalingEvent) error {
+	query := `
+		insert into scaling_events
+		(downscaler_name, rule_name, namespace_name, resource_type, resource_name,
+		 operation, replicas_before, replicas_after, outcome, error, created_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	event.CreatedAt = time.Now().UTC().Format(timestampLayout)
+
+	result, err := so.db.ExecContext(
+		ctx,
+		query,
+		event.DownscalerName,
+		event.RuleName,
+		event.NamespaceName,
+		event.ResourceType,
+		event.ResourceName,
+		event.Operation,
+		event.ReplicasBefore,
+		event.ReplicasAfter,
+		event.Outcome,
+		event.Error,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	event.ID = int(id)
+	return nil
+}
+
+// QueryEvents builds the conditions from the filter, MySQL would compare a datetime column with
+// the empty string of an unset bound instead of skipping it.
+func (so *MysqlScalingOperationStore) QueryEvents(ctx context.Context, filter ScalingEventFilter) ([]ScalingEvent, error) {
+	conditions := []string{"1 = 1"}
+	var args []any
+
+	if filter.DownscalerName != "" {
+		conditions = append(conditions, "downscaler_name = ?")
+		args = append(args, filter.DownscalerName)
+	}
+	if filter.NamespaceName != "" {
+		conditions = append(conditions, "namespace_name = ?")
+		args = append(args, filter.NamespaceName)
+	}
+	if filter.ResourceName != "" {
+		conditions = append(conditions, "resource_name = ?")
+		args = append(args, filter.ResourceName)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC().Format(timestampLayout))
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC().Format(timestampLayout))
+	}
+
+	query := `
+		select
+		 id, downscaler_name, rule_name, namespace_name, resource_type, resource_name,
+		 operation, replicas_before, replicas_after, outcome, error, created_at
+		 from scaling_events
+		 where ` + strings.Join(conditions, " and ") + `
+		 order by id desc
+	`
+
+	if filter.Limit > 0 {
+		query += " limit ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := so.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+func (so *MysqlScalingOperationStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		delete from scaling_events
+		where created_at < ?
+	`
+
+	result, err := so.db.ExecContext(
+		ctx,
+		query,
+		before.UTC().Format(timestampLayout),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,241 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+)
+
+func setMysqlTestDBClient(t *testing.T, connString string) *sql.DB {
+	db, err := sql.Open("mysql", connString)
+	if err != nil {
+		t.Fatalf("failed to connect to mysql db: %v", err)
+	}
+
+	return db
+}
+
+func TestMysqlScalingOperationLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		mysqlCredentials = "kubetime"
+		ctrImage         = "mysql:8.0.40"
+	)
+
+	ctr, err := mysql.Run(ctx,
+		ctrImage,
+		mysql.WithDatabase(mysqlCredentials),
+		mysql.WithUsername(mysqlCredentials),
+		mysql.WithPassword(mysqlCredentials),
+	)
+
+	defer func() {
+		if ctr == nil {
+			return
+		}
+		if err := ctr.Terminate(ctx); err != nil {
+			t.Log("error terminating the container: ", err)
+		}
+	}()
+
+	if err != nil {
+		t.Fatalf("unexpected error while initializing db container: %v", err)
+	}
+
+	conn, err := ctr.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("error fetching container connection string: %v", err)
+	}
+
+	db := setMysqlTestDBClient(t, conn)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("database ping fail: %v", err)
+	}
+	defer db.Close()
+
+	p := store.NewMysqlScalingOperationStore(db)
+
+	t.Run("Bootstrap", func(t *testing.T) {
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("bootstrap database scaling operation table should not return an error: %v", err)
+		}
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("bootstrapping an existing table should not return an error: %v", err)
+		}
+	})
+
+	scalingObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule",
+		ResourceName:        "test-name",
+		ResourceType:        "test-deployment",
+		Replicas:            5,
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		firstID := 1
+
+		if err := p.Insert(ctx, scalingObject); err != nil {
+			t.Fatalf("insert mysql operation failed: %v", err)
+		}
+		assert.Equal(t, firstID, scalingObject.ID)
+		assert.NotEmpty(t, scalingObject.CreatedAt)
+	})
+
+	updateObject := &store.ScalingOperation{
+		NamespaceName:       "test-namespace",
+		RuleNameDescription: "test-rule-updated",
+		ResourceName:        "test-name",
+		ResourceType:        "test-deployment",
+		Replicas:            10,
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		if err := p.Update(ctx, updateObject); err != nil {
+			t.Fatalf("update mysql operation failed: %v", err)
+		}
+	})
+
+	getObject := &store.ScalingOperation{
+		ResourceName:  "test-name",
+		NamespaceName: "test-namespace",
+		ResourceType:  "test-deployment",
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get updated object mysql error: %v", err)
+		}
+		assert.Equal(t, updateObject.NamespaceName, getObject.NamespaceName)
+		assert.Equal(t, updateObject.ResourceName, getObject.ResourceName)
+		assert.Equal(t, updateObject.RuleNameDescription, getObject.RuleNameDescription)
+		assert.Equal(t, updateObject.ResourceType, getObject.ResourceType)
+		assert.Equal(t, updateObject.UpdatedAt, getObject.UpdatedAt)
+	})
+
+	t.Run("SameNameDifferentResourceType", func(t *testing.T) {
+		hpaObject := &store.ScalingOperation{
+			NamespaceName:       "test-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "hpa",
+			Replicas:            2,
+			MaxReplicas:         8,
+		}
+		if err := p.Insert(ctx, hpaObject); err != nil {
+			t.Fatalf("insert mysql operation failed: %v", err)
+		}
+
+		getHpaObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "hpa"}
+		if err := p.Get(ctx, getHpaObject); err != nil {
+			t.Fatalf("get hpa object mysql error: %v", err)
+		}
+		assert.Equal(t, 2, getHpaObject.Replicas)
+		assert.Equal(t, 8, getHpaObject.MaxReplicas)
+
+		getDeploymentObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "test-deployment"}
+		if err := p.Get(ctx, getDeploymentObject); err != nil {
+			t.Fatalf("get deployment object mysql error: %v", err)
+		}
+		assert.Equal(t, updateObject.Replicas, getDeploymentObject.Replicas)
+
+		if err := p.Delete(ctx, hpaObject); err != nil {
+			t.Fatalf("delete mysql operation failed: %v", err)
+		}
+	})
+
+	t.Run("UpsertPerDownscaler", func(t *testing.T) {
+		first := &store.ScalingOperation{
+			DownscalerName:      "kubetime-scaler/first",
+			NamespaceName:       "upsert-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "deployments",
+			Replicas:            2,
+		}
+		second := *first
+		second.DownscalerName = "kubetime-scaler/second"
+		second.Replicas = 7
+
+		if err := p.Upsert(ctx, first); err != nil {
+			t.Fatalf("upsert mysql operation failed: %v", err)
+		}
+		if err := p.Upsert(ctx, &second); err != nil {
+			t.Fatalf("upsert mysql operation failed: %v", err)
+		}
+
+		first.Replicas = 3
+		if err := p.Upsert(ctx, first); err != nil {
+			t.Fatalf("upsert mysql operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "upsert-namespace"})
+		if err != nil {
+			t.Fatalf("list mysql operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+
+		getObject := &store.ScalingOperation{DownscalerName: "kubetime-scaler/first", ResourceName: "test-name", NamespaceName: "upsert-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get upserted object mysql error: %v", err)
+		}
+		assert.Equal(t, 3, getObject.Replicas)
+
+		getObject = &store.ScalingOperation{DownscalerName: "kubetime-scaler/second", ResourceName: "test-name", NamespaceName: "upsert-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get upserted object mysql error: %v", err)
+		}
+		assert.Equal(t, 7, getObject.Replicas)
+
+		assert.NoError(t, p.Delete(ctx, first))
+		assert.NoError(t, p.Delete(ctx, &second))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		otherNamespaceObject := &store.ScalingOperation{
+			NamespaceName:       "other-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "test-deployment",
+			Replicas:            3,
+		}
+		if err := p.Insert(ctx, otherNamespaceObject); err != nil {
+			t.Fatalf("insert mysql operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list mysql operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 1)
+		assert.Equal(t, "test-namespace", scalingObjects[0].NamespaceName)
+		assert.Equal(t, updateObject.Replicas, scalingObjects[0].Replicas)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{})
+		if err != nil {
+			t.Fatalf("list mysql operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		testScalingEvents(t, p)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := p.Delete(ctx, updateObject); err != nil {
+			t.Fatalf("delete mysql operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list mysql operations failed: %v", err)
+		}
+		assert.Empty(t, scalingObjects)
+	})
+}
@@ -3,23 +3,92 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type PostgresScalingOperationStore struct {
 	db *sql.DB
 }
 
+// postgresMigrations must only be appended to, the versions already released are applied on the
+// existing databases and are never run again.
+var postgresMigrations = []migration{
+	{
+		version:     1,
+		description: "create scaling_operations table",
+		up: execStatements(`
+			create table if not exists scaling_operations (
+				id serial primary key,
+				namespace_name varchar(50) not null,
+				rule_name_description text,
+				resource_name varchar(50) not null,
+				resource_type varchar(50),
+				replicas integer not null,
+				created_at timestamp default current_timestamp,
+				updated_at timestamp default current_timestamp
+			)
+		`),
+	},
+	{
+		version:     2,
+		description: "add max_replicas column for hpa records",
+		up:          execStatements(`alter table scaling_operations add column if not exists max_replicas integer`),
+	},
+	{
+		version:     3,
+		description: "key records by downscaler, namespace, resource type and resource name",
+		up: execStatements(
+			`alter table scaling_operations add column if not exists downscaler_name text not null default ''`,
+			// only the latest of the records that overwrote each other is kept.
+			`delete from scaling_operations a
+				using scaling_operations b
+				where a.downscaler_name = b.downscaler_name
+				and a.namespace_name = b.namespace_name
+				and a.resource_type is not distinct from b.resource_type
+				and a.resource_name = b.resource_name
+				and a.id < b.id`,
+			`create unique index if not exists scaling_operations_key
+				on scaling_operations (downscaler_name, namespace_name, resource_type, resource_name)`,
+		),
+	},
+	{
+		version:     4,
+		description: "create scaling_events history table",
+		up: execStatements(
+			`create table if not exists scaling_events (
+				id bigserial primary key,
+				downscaler_name text not null default '',
+				rule_name text not null default '',
+				namespace_name varchar(63) not null,
+				resource_type varchar(50) not null,
+				resource_name varchar(253) not null,
+				operation varchar(20) not null,
+				replicas_before integer not null,
+				replicas_after integer not null,
+				outcome varchar(20) not null,
+				error text not null default '',
+				created_at timestamp not null default (now() at time zone 'utc')
+			)`,
+			`create index if not exists scaling_events_created_at on scaling_events (created_at)`,
+			`create index if not exists scaling_events_namespace on scaling_events (namespace_name, created_at)`,
+		),
+	},
+}
+
 func NewPostgresScalingOperationStore(db *sql.DB) *PostgresScalingOperationStore {
 	return &PostgresScalingOperationStore{db: db}
 }
 
+// Get prefers the record of the downscaler over one written before the downscaler was part of the key.
 func (so *PostgresScalingOperationStore) Get(ctx context.Context, scalingObject *ScalingOperation) error {
 	query := `
 		select
-		 id, rule_name_description, resource_type,
-		 replicas, created_at, updated_at
+		 id, downscaler_name, rule_name_description, resource_type,
+		 replicas, coalesce(max_replicas, 0), created_at, updated_at
 		 from scaling_operations
-		 where resource_name = $1 and namespace_name = $2
+		 where resource_name = $1 and namespace_name = $2 and resource_type = $3 and downscaler_name in ($4, '')
+		 order by downscaler_name desc
+		 limit 1
 	`
 
 	return so.db.QueryRowContext(
@@ -27,54 +96,48 @@ func (so *PostgresScalingOperationStore) Get(ctx context.Context, scalingObject
 		query,
 		scalingObject.ResourceName,
 		scalingObject.NamespaceName,
+		scalingObject.ResourceType,
+		scalingObject.DownscalerName,
 	).Scan(
 		&scalingObject.ID,
+		&scalingObject.DownscalerName,
 		&scalingObject.RuleNameDescription,
 		&scalingObject.ResourceType,
 		&scalingObject.Replicas,
+		&scalingObject.MaxReplicas,
 		&scalingObject.CreatedAt,
 		&scalingObject.UpdatedAt,
 	)
 }
 
+// Bootstrap applies the pending schema migrations.
 func (so *PostgresScalingOperationStore) Bootstrap(ctx context.Context) error {
-	query := `
-		create table if not exists scaling_operations (
-			id serial primary key,
-			namespace_name varchar(50) not null,
-			rule_name_description text,
-			resource_name varchar(50) not null,
-			resource_type varchar(50),
-			replicas integer not null,
-			created_at timestamp default current_timestamp,
-			updated_at timestamp default current_timestamp
-		);
-	`
-
-	_, err := so.db.ExecContext(ctx, query)
-	if err != nil {
-		return err
-	}
+	return migrator{db: so.db, migrations: postgresMigrations, placeholder: "$"}.migrate(ctx)
+}
 
-	return nil
+// Ping reports whether the database answers, it backs the DatabaseAvailable condition.
+func (so *PostgresScalingOperationStore) Ping(ctx context.Context) error {
+	return so.db.PingContext(ctx)
 }
 
 func (so *PostgresScalingOperationStore) Insert(ctx context.Context, scalingObject *ScalingOperation) error {
 	query := `
 		insert into scaling_operations
-		(namespace_name, rule_name_description, resource_name, resource_type, replicas)
-		values ($1, $2, $3, $4, $5)
+		(downscaler_name, namespace_name, rule_name_description, resource_name, resource_type, replicas, max_replicas)
+		values ($1, $2, $3, $4, $5, $6, $7)
 		returning id, created_at
 	`
 
 	return so.db.QueryRowContext(
 		ctx,
 		query,
+		scalingObject.DownscalerName,
 		scalingObject.NamespaceName,
 		scalingObject.RuleNameDescription,
 		scalingObject.ResourceName,
 		scalingObject.ResourceType,
 		scalingObject.Replicas,
+		scalingObject.MaxReplicas,
 	).Scan(
 		&scalingObject.ID,
 		&scalingObject.CreatedAt,
@@ -84,9 +147,8 @@ func (so *PostgresScalingOperationStore) Insert(ctx context.Context, scalingObje
 func (so *PostgresScalingOperationStore) Update(ctx context.Context, scalingObject *ScalingOperation) error {
 	query := `
 		update scaling_operations
-		set replicas = $2, resource_name = $3, rule_name_description = $4,
-		resource_type = $5, updated_at = now()
-		where namespace_name = $1 and resource_name = $3
+		set replicas = $2, rule_name_description = $4, max_replicas = $6, updated_at = now()
+		where namespace_name = $1 and resource_name = $3 and resource_type = $5 and downscaler_name = $7
 		returning id, updated_at
 	`
 
@@ -98,9 +160,184 @@ func (so *PostgresScalingOperationStore) Update(ctx context.Context, scalingObje
 		scalingObject.ResourceName,
 		scalingObject.RuleNameDescription,
 		scalingObject.ResourceType,
+		scalingObject.MaxReplicas,
+		scalingObject.DownscalerName,
 	).Scan(
 		&scalingObject.ID,
 		&scalingObject.UpdatedAt,
 	)
 
 }
+
+func (so *PostgresScalingOperationStore) Upsert(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		insert into scaling_operations
+		(downscaler_name, namespace_name, rule_name_description, resource_name, resource_type, replicas, max_replicas)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		on conflict (downscaler_name, namespace_name, resource_type, resource_name) do update
+		set replicas = excluded.replicas,
+		 rule_name_description = excluded.rule_name_description,
+		 max_replicas = excluded.max_replicas,
+		 updated_at = now()
+		returning id, created_at, updated_at
+	`
+
+	return so.db.QueryRowContext(
+		ctx,
+		query,
+		scalingObject.DownscalerName,
+		scalingObject.NamespaceName,
+		scalingObject.RuleNameDescription,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+		scalingObject.Replicas,
+		scalingObject.MaxReplicas,
+	).Scan(
+		&scalingObject.ID,
+		&scalingObject.CreatedAt,
+		&scalingObject.UpdatedAt,
+	)
+}
+
+func (so *PostgresScalingOperationStore) List(ctx context.Context, filter ScalingOperationFilter) ([]ScalingOperation, error) {
+	query := `
+		select
+		 id, downscaler_name, namespace_name, rule_name_description, resource_name,
+		 resource_type, replicas, coalesce(max_replicas, 0), created_at, updated_at
+		 from scaling_operations
+		 where ($1::text = '' or namespace_name = $1) and ($2::text = '' or downscaler_name in ($2, ''))
+		 order by id
+	`
+
+	rows, err := so.db.QueryContext(
+		ctx,
+		query,
+		filter.NamespaceName,
+		filter.DownscalerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scalingObjects []ScalingOperation
+	for rows.Next() {
+		var scalingObject ScalingOperation
+		if err := rows.Scan(
+			&scalingObject.ID,
+			&scalingObject.DownscalerName,
+			&scalingObject.NamespaceName,
+			&scalingObject.RuleNameDescription,
+			&scalingObject.ResourceName,
+			&scalingObject.ResourceType,
+			&scalingObject.Replicas,
+			&scalingObject.MaxReplicas,
+			&scalingObject.CreatedAt,
+			&scalingObject.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		scalingObjects = append(scalingObjects, scalingObject)
+	}
+
+	return scalingObjects, rows.Err()
+}
+
+func (so *PostgresScalingOperationStore) Delete(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		delete from scaling_operations
+		where namespace_name = $1 and resource_name = $2 and resource_type = $3 and downscaler_name in ($4, '')
+	`
+
+	_, err := so.db.ExecContext(
+		ctx,
+		query,
+		scalingObject.NamespaceName,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+		scalingObject.DownscalerName,
+	)
+
+	return err
+}
+
+func (so *PostgresScalingOperationStore) RecordEvent(ctx context.Context, event *ScalingEvent) error {
+	query := `
+		insert into scaling_events
+		(downscaler_name, rule_name, namespace_name, resource_type, resource_name,
+		 operation, replicas_before, replicas_after, outcome, error, created_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		returning id, created_at
+	`
+
+	return so.db.QueryRowContext(
+		ctx,
+		query,
+		event.DownscalerName,
+		event.RuleName,
+		event.NamespaceName,
+		event.ResourceType,
+		event.ResourceName,
+		event.Operation,
+		event.ReplicasBefore,
+		event.ReplicasAfter,
+		event.Outcome,
+		event.Error,
+		time.Now().UTC(),
+	).Scan(
+		&event.ID,
+		&event.CreatedAt,
+	)
+}
+
+func (so *PostgresScalingOperationStore) QueryEvents(ctx context.Context, filter ScalingEventFilter) ([]ScalingEvent, error) {
+	query := `
+		select
+		 id, downscaler_name, rule_name, namespace_name, resource_type, resource_name,
+		 operation, replicas_before, replicas_after, outcome, error, created_at
+		 from scaling_events
+		 where ($1::text = '' or downscaler_name = $1)
+		 and ($2::text = '' or namespace_name = $2)
+		 and ($3::text = '' or resource_name = $3)
+		 and ($4::timestamp is null or created_at >= $4)
+		 and ($5::timestamp is null or created_at < $5)
+		 order by id desc
+		 limit $6
+	`
+
+	// a null limit is no limit for postgres.
+	rows, err := so.db.QueryContext(
+		ctx,
+		query,
+		filter.DownscalerName,
+		filter.NamespaceName,
+		filter.ResourceName,
+		sql.NullTime{Time: filter.Since.UTC(), Valid: !filter.Since.IsZero()},
+		sql.NullTime{Time: filter.Until.UTC(), Valid: !filter.Until.IsZero()},
+		sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+func (so *PostgresScalingOperationStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		delete from scaling_events
+		where created_at < $1
+	`
+
+	result, err := so.db.ExecContext(
+		ctx,
+		query,
+		before.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -108,6 +108,7 @@ func TestPostgresScalingOperationLifecycle(t *testing.T) {
 	getObject := &store.ScalingOperation{
 		ResourceName:  "test-name",
 		NamespaceName: "test-namespace",
+		ResourceType:  "test-deployment",
 	}
 
 	t.Run("Get", func(t *testing.T) {
@@ -121,4 +122,124 @@ func TestPostgresScalingOperationLifecycle(t *testing.T) {
 		assert.Equal(t, updateObject.UpdatedAt, getObject.UpdatedAt)
 	})
 
+	t.Run("SameNameDifferentResourceType", func(t *testing.T) {
+		hpaObject := &store.ScalingOperation{
+			NamespaceName:       "test-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "hpa",
+			Replicas:            2,
+			MaxReplicas:         8,
+		}
+		if err := p.Insert(ctx, hpaObject); err != nil {
+			t.Fatalf("insert postgres operation failed: %v", err)
+		}
+
+		getHpaObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "hpa"}
+		if err := p.Get(ctx, getHpaObject); err != nil {
+			t.Fatalf("get hpa object postgres error: %v", err)
+		}
+		assert.Equal(t, 2, getHpaObject.Replicas)
+		assert.Equal(t, 8, getHpaObject.MaxReplicas)
+
+		getDeploymentObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "test-deployment"}
+		if err := p.Get(ctx, getDeploymentObject); err != nil {
+			t.Fatalf("get deployment object postgres error: %v", err)
+		}
+		assert.Equal(t, updateObject.Replicas, getDeploymentObject.Replicas)
+
+		if err := p.Delete(ctx, hpaObject); err != nil {
+			t.Fatalf("delete postgres operation failed: %v", err)
+		}
+	})
+
+	t.Run("UpsertPerDownscaler", func(t *testing.T) {
+		first := &store.ScalingOperation{
+			DownscalerName:      "kubetime-scaler/first",
+			NamespaceName:       "upsert-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "deployments",
+			Replicas:            2,
+		}
+		second := *first
+		second.DownscalerName = "kubetime-scaler/second"
+		second.Replicas = 7
+
+		if err := p.Upsert(ctx, first); err != nil {
+			t.Fatalf("upsert postgres operation failed: %v", err)
+		}
+		if err := p.Upsert(ctx, &second); err != nil {
+			t.Fatalf("upsert postgres operation failed: %v", err)
+		}
+
+		first.Replicas = 3
+		if err := p.Upsert(ctx, first); err != nil {
+			t.Fatalf("upsert postgres operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "upsert-namespace"})
+		if err != nil {
+			t.Fatalf("list postgres operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+
+		getObject := &store.ScalingOperation{DownscalerName: "kubetime-scaler/first", ResourceName: "test-name", NamespaceName: "upsert-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get upserted object postgres error: %v", err)
+		}
+		assert.Equal(t, 3, getObject.Replicas)
+
+		getObject = &store.ScalingOperation{DownscalerName: "kubetime-scaler/second", ResourceName: "test-name", NamespaceName: "upsert-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get upserted object postgres error: %v", err)
+		}
+		assert.Equal(t, 7, getObject.Replicas)
+
+		assert.NoError(t, p.Delete(ctx, first))
+		assert.NoError(t, p.Delete(ctx, &second))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		otherNamespaceObject := &store.ScalingOperation{
+			NamespaceName:       "other-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "test-deployment",
+			Replicas:            3,
+		}
+		if err := p.Insert(ctx, otherNamespaceObject); err != nil {
+			t.Fatalf("insert postgres operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list postgres operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 1)
+		assert.Equal(t, "test-namespace", scalingObjects[0].NamespaceName)
+		assert.Equal(t, updateObject.Replicas, scalingObjects[0].Replicas)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{})
+		if err != nil {
+			t.Fatalf("list postgres operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		testScalingEvents(t, p)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := p.Delete(ctx, updateObject); err != nil {
+			t.Fatalf("delete postgres operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list postgres operations failed: %v", err)
+		}
+		assert.Empty(t, scalingObjects)
+	})
 }
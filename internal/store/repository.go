@@ -1,21 +1,65 @@
 package store
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/types"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
 
 type ScalingOperationStorer interface {
 	Bootstrap(context.Context) error
 	Get(context.Context, *ScalingOperation) error
 	Update(context.Context, *ScalingOperation) error
 	Insert(context.Context, *ScalingOperation) error
+	// Upsert atomically inserts the record or updates the one with the same key.
+	Upsert(context.Context, *ScalingOperation) error
+	List(context.Context, ScalingOperationFilter) ([]ScalingOperation, error)
+	Delete(context.Context, *ScalingOperation) error
+
+	// RecordEvent appends the event to the scaling history.
+	RecordEvent(context.Context, *ScalingEvent) error
+	// QueryEvents returns the events matching the filter, the most recent first.
+	QueryEvents(context.Context, ScalingEventFilter) ([]ScalingEvent, error)
+	// PurgeEvents deletes the events created before the given time and returns how many were deleted.
+	PurgeEvents(context.Context, time.Time) (int64, error)
+}
+
+// HealthChecker is implemented by the stores depending on a database, the others are always available.
+type HealthChecker interface {
+	Ping(context.Context) error
+}
+
+// KindRegistry is implemented by the stores reading the scaled objects themselves, they learn the
+// kind of the custom resources declared in scalableResources from their scaler.
+type KindRegistry interface {
+	RegisterKind(types.ResourceType, schema.GroupVersionKind)
+}
+
+// ScalingOperationFilter narrows List results. Empty fields match every record.
+type ScalingOperationFilter struct {
+	DownscalerName string
+	NamespaceName  string
+}
+
+// DownscalerKey returns the DownscalerName of the records written by a downscaler object.
+func DownscalerKey(namespace, name string) string {
+	return namespace + "/" + name
 }
 
+// ScalingOperation records the replicas of an object before a downscale. Records are keyed by
+// downscaler, namespace, resource type and resource name. Records written before the downscaler
+// was part of the key have an empty DownscalerName and are matched by every downscaler.
 type ScalingOperation struct {
 	ID                  int    `json:"id"`
+	DownscalerName      string `json:"downscaler_name"`
 	NamespaceName       string `json:"namespace_name"`
 	RuleNameDescription string `json:"rule_name_description"`
 	ResourceName        string `json:"resource_name"`
 	ResourceType        string `json:"resource_type"`
-	Replicas            int    `json:"replicas"`
+	Replicas            int    `json:"replicas"`     // cronjobs records hold 1 when the cronjob was suspended before the downscale
+	MaxReplicas         int    `json:"max_replicas"` // only used by hpa records, where Replicas holds minReplicas
 	CreatedAt           string `json:"created_at"`
 	UpdatedAt           string `json:"updated_at"`
 }
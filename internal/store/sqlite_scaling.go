@@ -3,23 +3,104 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type SqliteScalingOperationStore struct {
 	db *sql.DB
 }
 
+// sqliteMigrations must only be appended to, the versions already released are applied on the
+// existing databases and are never run again.
+var sqliteMigrations = []migration{
+	{
+		version:     1,
+		description: "create scaling_operations table",
+		up: execStatements(`
+			create table if not exists scaling_operations (
+				id integer primary key autoincrement,
+				namespace_name varchar(50) not null,
+				rule_name_description text,
+				resource_name varchar(50) not null,
+				resource_type varchar(50),
+				replicas integer not null,
+				created_at datetime default current_timestamp,
+				updated_at datetime default current_timestamp
+			);
+		`),
+	},
+	{
+		version:     2,
+		description: "add max_replicas column for hpa records",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			// databases bootstrapped before the migrations may have the column already
+			// and sqlite has no "add column if not exists".
+			var found int
+			if err := tx.QueryRowContext(ctx,
+				`select count(*) from pragma_table_info('scaling_operations') where name = 'max_replicas';`,
+			).Scan(&found); err != nil {
+				return err
+			}
+
+			if found == 0 {
+				_, err := tx.ExecContext(ctx, `alter table scaling_operations add column max_replicas integer;`)
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		version:     3,
+		description: "key records by downscaler, namespace, resource type and resource name",
+		up: execStatements(
+			`alter table scaling_operations add column downscaler_name text not null default '';`,
+			// only the latest of the records that overwrote each other is kept.
+			`delete from scaling_operations where id not in (
+				select max(id) from scaling_operations
+				group by downscaler_name, namespace_name, resource_type, resource_name
+			);`,
+			`create unique index if not exists scaling_operations_key
+				on scaling_operations (downscaler_name, namespace_name, resource_type, resource_name);`,
+		),
+	},
+	{
+		version:     4,
+		description: "create scaling_events history table",
+		up: execStatements(
+			`create table if not exists scaling_events (
+				id integer primary key autoincrement,
+				downscaler_name text not null default '',
+				rule_name text not null default '',
+				namespace_name varchar(63) not null,
+				resource_type varchar(50) not null,
+				resource_name varchar(253) not null,
+				operation varchar(20) not null,
+				replicas_before integer not null,
+				replicas_after integer not null,
+				outcome varchar(20) not null,
+				error text not null default '',
+				created_at datetime not null default current_timestamp
+			);`,
+			`create index if not exists scaling_events_created_at on scaling_events (created_at);`,
+			`create index if not exists scaling_events_namespace on scaling_events (namespace_name, created_at);`,
+		),
+	},
+}
+
 func NewSqliteScalingOperationStore(db *sql.DB) *SqliteScalingOperationStore {
 	return &SqliteScalingOperationStore{db: db}
 }
 
+// Get prefers the record of the downscaler over one written before the downscaler was part of the key.
 func (so *SqliteScalingOperationStore) Get(ctx context.Context, scalingObject *ScalingOperation) error {
 	query := `
 		select
-		 id, rule_name_description, resource_type,
-		 replicas, created_at, updated_at
+		 id, downscaler_name, rule_name_description, resource_type,
+		 replicas, coalesce(max_replicas, 0), created_at, updated_at
 		 from scaling_operations
-		 where resource_name = ? and namespace_name = ?;
+		 where resource_name = ? and namespace_name = ? and resource_type = ? and downscaler_name in (?, '')
+		 order by downscaler_name desc
+		 limit 1;
 	`
 
 	return so.db.QueryRowContext(
@@ -27,54 +108,48 @@ func (so *SqliteScalingOperationStore) Get(ctx context.Context, scalingObject *S
 		query,
 		scalingObject.ResourceName,
 		scalingObject.NamespaceName,
+		scalingObject.ResourceType,
+		scalingObject.DownscalerName,
 	).Scan(
 		&scalingObject.ID,
+		&scalingObject.DownscalerName,
 		&scalingObject.RuleNameDescription,
 		&scalingObject.ResourceType,
 		&scalingObject.Replicas,
+		&scalingObject.MaxReplicas,
 		&scalingObject.CreatedAt,
 		&scalingObject.UpdatedAt,
 	)
 }
 
+// Bootstrap applies the pending schema migrations.
 func (so *SqliteScalingOperationStore) Bootstrap(ctx context.Context) error {
-	query := `
-		create table if not exists scaling_operations (
-			id integer primary key autoincrement,
-			namespace_name varchar(50) not null,
-			rule_name_description text,
-			resource_name varchar(50) not null,
-			resource_type varchar(50),
-			replicas integer not null,
-			created_at datetime default current_timestamp,
-			updated_at datetime default current_timestamp
-		);
-	`
-
-	_, err := so.db.ExecContext(ctx, query)
-	if err != nil {
-		return err
-	}
+	return migrator{db: so.db, migrations: sqliteMigrations, placeholder: "?"}.migrate(ctx)
+}
 
-	return nil
+// Ping reports whether the database answers, it backs the DatabaseAvailable condition.
+func (so *SqliteScalingOperationStore) Ping(ctx context.Context) error {
+	return so.db.PingContext(ctx)
 }
 
 func (so *SqliteScalingOperationStore) Insert(ctx context.Context, scalingObject *ScalingOperation) error {
 	query := `
 		insert into scaling_operations
-		(namespace_name, rule_name_description, resource_name, resource_type, replicas)
-		values (?, ?, ?, ?, ?)
+		(downscaler_name, namespace_name, rule_name_description, resource_name, resource_type, replicas, max_replicas)
+		values (?, ?, ?, ?, ?, ?, ?)
 		returning id, created_at;
 	`
 
 	return so.db.QueryRowContext(
 		ctx,
 		query,
+		scalingObject.DownscalerName,
 		scalingObject.NamespaceName,
 		scalingObject.RuleNameDescription,
 		scalingObject.ResourceName,
 		scalingObject.ResourceType,
 		scalingObject.Replicas,
+		scalingObject.MaxReplicas,
 	).Scan(
 		&scalingObject.ID,
 		&scalingObject.CreatedAt,
@@ -84,8 +159,8 @@ func (so *SqliteScalingOperationStore) Insert(ctx context.Context, scalingObject
 func (so *SqliteScalingOperationStore) Update(ctx context.Context, scalingObject *ScalingOperation) error {
 	query := `
 		update scaling_operations
-		set replicas = ?, rule_name_description = ?, updated_at = current_timestamp
-		where namespace_name = ? and resource_name = ? and resource_type = ?
+		set replicas = ?, rule_name_description = ?, max_replicas = ?, updated_at = current_timestamp
+		where downscaler_name = ? and namespace_name = ? and resource_name = ? and resource_type = ?
 		returning id, updated_at;
 	`
 
@@ -94,11 +169,207 @@ func (so *SqliteScalingOperationStore) Update(ctx context.Context, scalingObject
 		query,
 		scalingObject.Replicas,
 		scalingObject.RuleNameDescription,
+		scalingObject.MaxReplicas,
+		scalingObject.DownscalerName,
+		scalingObject.NamespaceName,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+	).Scan(
+		&scalingObject.ID,
+		&scalingObject.UpdatedAt,
+	)
+}
+
+func (so *SqliteScalingOperationStore) Upsert(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		insert into scaling_operations
+		(downscaler_name, namespace_name, rule_name_description, resource_name, resource_type, replicas, max_replicas)
+		values (?, ?, ?, ?, ?, ?, ?)
+		on conflict (downscaler_name, namespace_name, resource_type, resource_name) do update
+		set replicas = excluded.replicas,
+		 rule_name_description = excluded.rule_name_description,
+		 max_replicas = excluded.max_replicas,
+		 updated_at = current_timestamp
+		returning id, created_at, updated_at;
+	`
+
+	return so.db.QueryRowContext(
+		ctx,
+		query,
+		scalingObject.DownscalerName,
 		scalingObject.NamespaceName,
+		scalingObject.RuleNameDescription,
 		scalingObject.ResourceName,
 		scalingObject.ResourceType,
+		scalingObject.Replicas,
+		scalingObject.MaxReplicas,
 	).Scan(
 		&scalingObject.ID,
+		&scalingObject.CreatedAt,
 		&scalingObject.UpdatedAt,
 	)
 }
+
+func (so *SqliteScalingOperationStore) List(ctx context.Context, filter ScalingOperationFilter) ([]ScalingOperation, error) {
+	query := `
+		select
+		 id, downscaler_name, namespace_name, rule_name_description, resource_name,
+		 resource_type, replicas, coalesce(max_replicas, 0), created_at, updated_at
+		 from scaling_operations
+		 where (? = '' or namespace_name = ?) and (? = '' or downscaler_name in (?, ''))
+		 order by id;
+	`
+
+	rows, err := so.db.QueryContext(
+		ctx,
+		query,
+		filter.NamespaceName,
+		filter.NamespaceName,
+		filter.DownscalerName,
+		filter.DownscalerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scalingObjects []ScalingOperation
+	for rows.Next() {
+		var scalingObject ScalingOperation
+		if err := rows.Scan(
+			&scalingObject.ID,
+			&scalingObject.DownscalerName,
+			&scalingObject.NamespaceName,
+			&scalingObject.RuleNameDescription,
+			&scalingObject.ResourceName,
+			&scalingObject.ResourceType,
+			&scalingObject.Replicas,
+			&scalingObject.MaxReplicas,
+			&scalingObject.CreatedAt,
+			&scalingObject.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		scalingObjects = append(scalingObjects, scalingObject)
+	}
+
+	return scalingObjects, rows.Err()
+}
+
+func (so *SqliteScalingOperationStore) Delete(ctx context.Context, scalingObject *ScalingOperation) error {
+	query := `
+		delete from scaling_operations
+		where namespace_name = ? and resource_name = ? and resource_type = ? and downscaler_name in (?, '');
+	`
+
+	_, err := so.db.ExecContext(
+		ctx,
+		query,
+		scalingObject.NamespaceName,
+		scalingObject.ResourceName,
+		scalingObject.ResourceType,
+		scalingObject.DownscalerName,
+	)
+
+	return err
+}
+
+func (so *SqliteScalingOperationStore) RecordEvent(ctx context.Context, event *ScalingEvent) error {
+	query := `
+		insert into scaling_events
+		(downscaler_name, rule_name, namespace_name, resource_type, resource_name,
+		 operation, replicas_before, replicas_after, outcome, error, created_at)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		returning id;
+	`
+
+	event.CreatedAt = time.Now().UTC().Format(timestampLayout)
+
+	return so.db.QueryRowContext(
+		ctx,
+		query,
+		event.DownscalerName,
+		event.RuleName,
+		event.NamespaceName,
+		event.ResourceType,
+		event.ResourceName,
+		event.Operation,
+		event.ReplicasBefore,
+		event.ReplicasAfter,
+		event.Outcome,
+		event.Error,
+		event.CreatedAt,
+	).Scan(
+		&event.ID,
+	)
+}
+
+func (so *SqliteScalingOperationStore) QueryEvents(ctx context.Context, filter ScalingEventFilter) ([]ScalingEvent, error) {
+	query := `
+		select
+		 id, downscaler_name, rule_name, namespace_name, resource_type, resource_name,
+		 operation, replicas_before, replicas_after, outcome, error, created_at
+		 from scaling_events
+		 where (? = '' or downscaler_name = ?)
+		 and (? = '' or namespace_name = ?)
+		 and (? = '' or resource_name = ?)
+		 and (? = '' or created_at >= ?)
+		 and (? = '' or created_at < ?)
+		 order by id desc
+		 limit ?;
+	`
+
+	var since, until string
+	if !filter.Since.IsZero() {
+		since = filter.Since.UTC().Format(timestampLayout)
+	}
+	if !filter.Until.IsZero() {
+		until = filter.Until.UTC().Format(timestampLayout)
+	}
+
+	// a negative limit is no limit for sqlite.
+	limit := -1
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	rows, err := so.db.QueryContext(
+		ctx,
+		query,
+		filter.DownscalerName,
+		filter.DownscalerName,
+		filter.NamespaceName,
+		filter.NamespaceName,
+		filter.ResourceName,
+		filter.ResourceName,
+		since,
+		since,
+		until,
+		until,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+func (so *SqliteScalingOperationStore) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		delete from scaling_events
+		where created_at < ?;
+	`
+
+	result, err := so.db.ExecContext(
+		ctx,
+		query,
+		before.UTC().Format(timestampLayout),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
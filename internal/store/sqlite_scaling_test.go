@@ -3,7 +3,9 @@ package store_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/adalbertjnr/kubetime-scaler/internal/store"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +31,9 @@ func TestSqliteScalingOperationLifecycle(t *testing.T) {
 		if err := p.Bootstrap(ctx); err != nil {
 			t.Fatalf("bootstrap database scaling operation table should not return an error: %v", err)
 		}
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("bootstrapping an existing table should not return an error: %v", err)
+		}
 	})
 
 	scalingObject := &store.ScalingOperation{
@@ -66,6 +71,7 @@ func TestSqliteScalingOperationLifecycle(t *testing.T) {
 	getObject := &store.ScalingOperation{
 		ResourceName:  "test-name",
 		NamespaceName: "test-namespace",
+		ResourceType:  "test-deployment",
 	}
 
 	t.Run("Get", func(t *testing.T) {
@@ -78,4 +84,311 @@ func TestSqliteScalingOperationLifecycle(t *testing.T) {
 		assert.Equal(t, updateObject.ResourceType, getObject.ResourceType)
 		assert.Equal(t, updateObject.UpdatedAt, getObject.UpdatedAt)
 	})
+
+	t.Run("SameNameDifferentResourceType", func(t *testing.T) {
+		hpaObject := &store.ScalingOperation{
+			NamespaceName:       "test-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "hpa",
+			Replicas:            2,
+			MaxReplicas:         8,
+		}
+		if err := p.Insert(ctx, hpaObject); err != nil {
+			t.Fatalf("insert sqlite operation failed: %v", err)
+		}
+
+		getHpaObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "hpa"}
+		if err := p.Get(ctx, getHpaObject); err != nil {
+			t.Fatalf("get hpa object sqlite error: %v", err)
+		}
+		assert.Equal(t, 2, getHpaObject.Replicas)
+		assert.Equal(t, 8, getHpaObject.MaxReplicas)
+
+		getDeploymentObject := &store.ScalingOperation{ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "test-deployment"}
+		if err := p.Get(ctx, getDeploymentObject); err != nil {
+			t.Fatalf("get deployment object sqlite error: %v", err)
+		}
+		assert.Equal(t, updateObject.Replicas, getDeploymentObject.Replicas)
+
+		if err := p.Delete(ctx, hpaObject); err != nil {
+			t.Fatalf("delete sqlite operation failed: %v", err)
+		}
+	})
+
+	t.Run("UpsertPerDownscaler", func(t *testing.T) {
+		first := &store.ScalingOperation{
+			DownscalerName:      "kubetime-scaler/first",
+			NamespaceName:       "upsert-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "deployments",
+			Replicas:            2,
+		}
+		second := *first
+		second.DownscalerName = "kubetime-scaler/second"
+		second.Replicas = 7
+
+		if err := p.Upsert(ctx, first); err != nil {
+			t.Fatalf("upsert sqlite operation failed: %v", err)
+		}
+		if err := p.Upsert(ctx, &second); err != nil {
+			t.Fatalf("upsert sqlite operation failed: %v", err)
+		}
+
+		first.Replicas = 3
+		if err := p.Upsert(ctx, first); err != nil {
+			t.Fatalf("upsert sqlite operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "upsert-namespace"})
+		if err != nil {
+			t.Fatalf("list sqlite operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+
+		getObject := &store.ScalingOperation{DownscalerName: "kubetime-scaler/first", ResourceName: "test-name", NamespaceName: "upsert-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get upserted object sqlite error: %v", err)
+		}
+		assert.Equal(t, 3, getObject.Replicas)
+
+		getObject = &store.ScalingOperation{DownscalerName: "kubetime-scaler/second", ResourceName: "test-name", NamespaceName: "upsert-namespace", ResourceType: "deployments"}
+		if err := p.Get(ctx, getObject); err != nil {
+			t.Fatalf("get upserted object sqlite error: %v", err)
+		}
+		assert.Equal(t, 7, getObject.Replicas)
+
+		assert.NoError(t, p.Delete(ctx, first))
+		assert.NoError(t, p.Delete(ctx, &second))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		otherNamespaceObject := &store.ScalingOperation{
+			NamespaceName:       "other-namespace",
+			RuleNameDescription: "test-rule",
+			ResourceName:        "test-name",
+			ResourceType:        "test-deployment",
+			Replicas:            3,
+		}
+		if err := p.Insert(ctx, otherNamespaceObject); err != nil {
+			t.Fatalf("insert sqlite operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list sqlite operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 1)
+		assert.Equal(t, "test-namespace", scalingObjects[0].NamespaceName)
+		assert.Equal(t, updateObject.Replicas, scalingObjects[0].Replicas)
+
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{})
+		if err != nil {
+			t.Fatalf("list sqlite operations failed: %v", err)
+		}
+		assert.Len(t, scalingObjects, 2)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := p.Delete(ctx, updateObject); err != nil {
+			t.Fatalf("delete sqlite operation failed: %v", err)
+		}
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{NamespaceName: "test-namespace"})
+		if err != nil {
+			t.Fatalf("list sqlite operations failed: %v", err)
+		}
+		assert.Empty(t, scalingObjects)
+	})
+}
+
+func TestSqliteMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("LegacyDatabase", func(t *testing.T) {
+		db := setSqliteTestDBClient(t)
+		defer db.Close()
+
+		// the table as created by releases without migrations, holding a record to keep.
+		if _, err := db.ExecContext(ctx, `
+			create table scaling_operations (
+				id integer primary key autoincrement,
+				namespace_name varchar(50) not null,
+				rule_name_description text,
+				resource_name varchar(50) not null,
+				resource_type varchar(50),
+				replicas integer not null,
+				created_at datetime default current_timestamp,
+				updated_at datetime default current_timestamp
+			);
+		`); err != nil {
+			t.Fatalf("creating legacy table: %v", err)
+		}
+		// without a unique key the same object could be recorded twice, the latest record wins.
+		for _, replicas := range []int{9, 4} {
+			if _, err := db.ExecContext(ctx, `
+				insert into scaling_operations (namespace_name, rule_name_description, resource_name, resource_type, replicas)
+				values ('test-namespace', 'test-rule', 'test-name', 'deployments', ?);
+			`, replicas); err != nil {
+				t.Fatalf("inserting legacy record: %v", err)
+			}
+		}
+
+		p := store.NewSqliteScalingOperationStore(db)
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("migrating legacy database should not return an error: %v", err)
+		}
+
+		var versions []int
+		rows, err := db.QueryContext(ctx, `select version from schema_migrations order by version`)
+		if err != nil {
+			t.Fatalf("reading schema_migrations: %v", err)
+		}
+		for rows.Next() {
+			var version int
+			assert.NoError(t, rows.Scan(&version))
+			versions = append(versions, version)
+		}
+		rows.Close()
+		assert.Equal(t, []int{1, 2, 3, 4}, versions)
+
+		// legacy records have no downscaler and are found by any of them.
+		getObject := &store.ScalingOperation{DownscalerName: "kubetime-scaler/kubetime-scaler", ResourceName: "test-name", NamespaceName: "test-namespace", ResourceType: "deployments"}
+		assert.NoError(t, p.Get(ctx, getObject))
+		assert.Equal(t, 4, getObject.Replicas)
+		assert.Equal(t, 0, getObject.MaxReplicas)
+		assert.Empty(t, getObject.DownscalerName)
+
+		scalingObjects, err := p.List(ctx, store.ScalingOperationFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, scalingObjects, 1)
+
+		assert.NoError(t, p.Delete(ctx, getObject))
+		scalingObjects, err = p.List(ctx, store.ScalingOperationFilter{})
+		assert.NoError(t, err)
+		assert.Empty(t, scalingObjects)
+	})
+
+	t.Run("NewerSchemaIsRefused", func(t *testing.T) {
+		db := setSqliteTestDBClient(t)
+		defer db.Close()
+
+		p := store.NewSqliteScalingOperationStore(db)
+		if err := p.Bootstrap(ctx); err != nil {
+			t.Fatalf("bootstrap should not return an error: %v", err)
+		}
+
+		if _, err := db.ExecContext(ctx, `insert into schema_migrations (version, description) values (1000, 'from a newer release')`); err != nil {
+			t.Fatalf("recording newer migration: %v", err)
+		}
+
+		assert.ErrorContains(t, p.Bootstrap(ctx), "newer than the latest supported version")
+	})
+}
+
+func TestSqliteScalingEvents(t *testing.T) {
+	db := setSqliteTestDBClient(t)
+	defer db.Close()
+
+	p := store.NewSqliteScalingOperationStore(db)
+	if err := p.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("bootstrap should not return an error: %v", err)
+	}
+
+	testScalingEvents(t, p)
+}
+
+func testScalingEvents(t *testing.T, p store.ScalingOperationStorer) {
+	ctx := context.Background()
+
+	scalingObject := store.ScalingOperation{
+		DownscalerName:      "kubetime-scaler/kubetime-scaler",
+		RuleNameDescription: "nightly",
+		NamespaceName:       "payments",
+		ResourceName:        "payments-api",
+		ResourceType:        "deployments",
+	}
+	otherObject := scalingObject
+	otherObject.NamespaceName = "orders"
+	otherObject.ResourceName = "orders-api"
+
+	for _, event := range []*store.ScalingEvent{
+		store.NewScalingEvent(scalingObject, "downscale", 3, 0, nil),
+		store.NewScalingEvent(otherObject, "downscale", 2, 0, errors.New("forbidden")),
+		store.NewScalingEvent(scalingObject, "upscale", 0, 3, nil),
+	} {
+		if err := p.RecordEvent(ctx, event); err != nil {
+			t.Fatalf("record scaling event failed: %v", err)
+		}
+		assert.NotZero(t, event.ID)
+		assert.NotEmpty(t, event.CreatedAt)
+	}
+
+	t.Run("QueryByNamespace", func(t *testing.T) {
+		events, err := p.QueryEvents(ctx, store.ScalingEventFilter{NamespaceName: "payments"})
+		if err != nil {
+			t.Fatalf("query scaling events failed: %v", err)
+		}
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, "upscale", events[0].Operation)
+			assert.Equal(t, "downscale", events[1].Operation)
+			assert.Equal(t, 3, events[1].ReplicasBefore)
+			assert.Equal(t, 0, events[1].ReplicasAfter)
+			assert.Equal(t, "nightly", events[1].RuleName)
+			assert.Equal(t, "kubetime-scaler/kubetime-scaler", events[1].DownscalerName)
+			assert.Equal(t, store.EventOutcomeSucceeded, events[1].Outcome)
+		}
+
+		events, err = p.QueryEvents(ctx, store.ScalingEventFilter{NamespaceName: "orders"})
+		if err != nil {
+			t.Fatalf("query scaling events failed: %v", err)
+		}
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, store.EventOutcomeFailed, events[0].Outcome)
+			assert.Equal(t, "forbidden", events[0].Error)
+		}
+	})
+
+	t.Run("QueryByTimeRangeAndLimit", func(t *testing.T) {
+		events, err := p.QueryEvents(ctx, store.ScalingEventFilter{Since: time.Now().Add(-time.Hour), Until: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("query scaling events failed: %v", err)
+		}
+		assert.Len(t, events, 3)
+
+		events, err = p.QueryEvents(ctx, store.ScalingEventFilter{Until: time.Now().Add(-time.Hour)})
+		if err != nil {
+			t.Fatalf("query scaling events failed: %v", err)
+		}
+		assert.Empty(t, events)
+
+		events, err = p.QueryEvents(ctx, store.ScalingEventFilter{Limit: 1})
+		if err != nil {
+			t.Fatalf("query scaling events failed: %v", err)
+		}
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "upscale", events[0].Operation)
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		purged, err := p.PurgeEvents(ctx, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("purge scaling events failed: %v", err)
+		}
+		assert.Zero(t, purged)
+
+		purged, err = p.PurgeEvents(ctx, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("purge scaling events failed: %v", err)
+		}
+		assert.Equal(t, int64(3), purged)
+
+		events, err := p.QueryEvents(ctx, store.ScalingEventFilter{})
+		if err != nil {
+			t.Fatalf("query scaling events failed: %v", err)
+		}
+		assert.Empty(t, events)
+	})
 }
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// StateVersion is written to every export, an import refuses the documents of another version.
+	StateVersion = 1
+
+	StateFormatJSON = "json"
+	StateFormatYAML = "yaml"
+)
+
+// State is the document written by the state export and read by the state import, holding every
+// record of a store so it can be moved to another driver or cluster.
+type State struct {
+	Version           int                `json:"version"`
+	ExportedAt        string             `json:"exported_at"`
+	ScalingOperations []ScalingOperation `json:"scaling_operations"`
+}
+
+// ExportState reads the records of the store matching the filter.
+func ExportState(ctx context.Context, storer ScalingOperationStorer, filter ScalingOperationFilter) (*State, error) {
+	scalingObjects, err := storer.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if scalingObjects == nil {
+		scalingObjects = []ScalingOperation{}
+	}
+
+	return &State{
+		Version:           StateVersion,
+		ExportedAt:        time.Now().UTC().Format(time.RFC3339),
+		ScalingOperations: scalingObjects,
+	}, nil
+}
+
+// ImportState upserts every record of the state into the store, so importing the same document
+// twice leaves the store as after the first import. The ids are assigned by the store.
+func ImportState(ctx context.Context, storer ScalingOperationStorer, state *State) (int, error) {
+	if state.Version != StateVersion {
+		return 0, fmt.Errorf("unsupported state version %d, expected %d", state.Version, StateVersion)
+	}
+
+	for i, scalingObject := range state.ScalingOperations {
+		scalingObject.ID = 0
+		if err := storer.Upsert(ctx, &scalingObject); err != nil {
+			return i, fmt.Errorf("importing %s %s/%s: %w",
+				scalingObject.ResourceType, scalingObject.NamespaceName, scalingObject.ResourceName, err)
+		}
+	}
+
+	return len(state.ScalingOperations), nil
+}
+
+func EncodeState(w io.Writer, state *State, format string) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch format {
+	case StateFormatJSON:
+		data, err = json.MarshalIndent(state, "", "  ")
+		data = append(data, '\n')
+	case StateFormatYAML:
+		data, err = yaml.Marshal(state)
+	default:
+		return fmt.Errorf("unsupported state format %q, expected %s or %s", format, StateFormatJSON, StateFormatYAML)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// DecodeState reads both formats, json being valid yaml.
+func DecodeState(r io.Reader) (*State, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := yaml.UnmarshalStrict(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
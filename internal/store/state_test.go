@@ -0,0 +1,89 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/adalbertjnr/kubetime-scaler/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	source := store.NewMemoryScalingOperationStore()
+	for _, scalingObject := range []*store.ScalingOperation{
+		{DownscalerName: "kubetime-scaler/first", NamespaceName: "payments", ResourceName: "payments-api", ResourceType: "deployments", RuleNameDescription: "nightly", Replicas: 3},
+		{DownscalerName: "kubetime-scaler/first", NamespaceName: "payments", ResourceName: "payments-api", ResourceType: "hpa", Replicas: 2, MaxReplicas: 6},
+		{NamespaceName: "orders", ResourceName: "orders-db", ResourceType: "statefulsets", Replicas: 1},
+	} {
+		if err := source.Insert(ctx, scalingObject); err != nil {
+			t.Fatalf("insert memory operation failed: %v", err)
+		}
+	}
+
+	for _, format := range []string{store.StateFormatJSON, store.StateFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			state, err := store.ExportState(ctx, source, store.ScalingOperationFilter{})
+			if err != nil {
+				t.Fatalf("export state failed: %v", err)
+			}
+			assert.Len(t, state.ScalingOperations, 3)
+
+			var buf bytes.Buffer
+			if err := store.EncodeState(&buf, state, format); err != nil {
+				t.Fatalf("encode state failed: %v", err)
+			}
+
+			decoded, err := store.DecodeState(&buf)
+			if err != nil {
+				t.Fatalf("decode state failed: %v", err)
+			}
+
+			target := store.NewMemoryScalingOperationStore()
+			// importing twice must not duplicate the records.
+			for range 2 {
+				imported, err := store.ImportState(ctx, target, decoded)
+				if err != nil {
+					t.Fatalf("import state failed: %v", err)
+				}
+				assert.Equal(t, 3, imported)
+			}
+
+			scalingObjects, err := target.List(ctx, store.ScalingOperationFilter{})
+			if err != nil {
+				t.Fatalf("list memory operations failed: %v", err)
+			}
+			assert.Len(t, scalingObjects, 3)
+
+			getObject := &store.ScalingOperation{DownscalerName: "kubetime-scaler/first", NamespaceName: "payments", ResourceName: "payments-api", ResourceType: "hpa"}
+			if err := target.Get(ctx, getObject); err != nil {
+				t.Fatalf("get imported object failed: %v", err)
+			}
+			assert.Equal(t, 2, getObject.Replicas)
+			assert.Equal(t, 6, getObject.MaxReplicas)
+		})
+	}
+
+	t.Run("NamespaceFilter", func(t *testing.T) {
+		state, err := store.ExportState(ctx, source, store.ScalingOperationFilter{NamespaceName: "orders"})
+		if err != nil {
+			t.Fatalf("export state failed: %v", err)
+		}
+		if assert.Len(t, state.ScalingOperations, 1) {
+			assert.Equal(t, "orders-db", state.ScalingOperations[0].ResourceName)
+		}
+	})
+
+	t.Run("RejectsInvalidDocuments", func(t *testing.T) {
+		_, err := store.ImportState(ctx, store.NewMemoryScalingOperationStore(), &store.State{Version: 2})
+		assert.Error(t, err)
+
+		_, err = store.DecodeState(strings.NewReader("version: 1\nscaling_operation: []\n"))
+		assert.Error(t, err)
+
+		assert.Error(t, store.EncodeState(&bytes.Buffer{}, &store.State{}, "xml"))
+	})
+}
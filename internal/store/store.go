@@ -1,49 +1,127 @@
 package store
 
 import (
+	"context"
+	"database/sql"
 	"strings"
 
 	"github.com/adalbertjnr/kubetime-scaler/internal/db"
+	"github.com/adalbertjnr/kubetime-scaler/internal/utils"
 	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	sqliteDriver   = "sqlite"
 	postgresDriver = "postgres"
+	mysqlDriver    = "mysql"
+	// annotationsDriver records the replicas as annotations on the scaled objects, no database is involved.
+	annotationsDriver = "annotations"
+	// configMapDriver keeps the records in configmaps of the controller namespace.
+	configMapDriver = "configmap"
 
 	sqlitePersistencePath = "/home/nonroot/data_db"
 )
 
 type Persistence struct {
 	ScalingOperation ScalingOperationStorer
+	// Volatile is set for stores losing their records on restart. A record missing from them is
+	// expected and the scalers fall back to the upscale replicas of the rule.
+	Volatile bool
 }
 
-func New(log logr.Logger, enableDatabase bool, c db.Config) *Persistence {
+// NewMemory returns the in-memory persistence used when no database is configured.
+func NewMemory() *Persistence {
+	return &Persistence{ScalingOperation: NewMemoryScalingOperationStore(), Volatile: true}
+}
+
+// Ping checks the database behind the store, if any.
+func (p *Persistence) Ping(ctx context.Context) error {
+	if checker, ok := p.ScalingOperation.(HealthChecker); ok {
+		return checker.Ping(ctx)
+	}
+	return nil
+}
+
+// New returns the store of the configured driver. apiReader reads the configmaps of the configmap
+// driver without going through the cache of k8sClient.
+func New(log logr.Logger, enableDatabase bool, c db.Config, k8sClient client.Client, apiReader client.Reader) *Persistence {
 	if !enableDatabase {
-		return nil
+		log.Info("database", "initializing store with", "memory_store", "replicas are kept while the pod lives")
+		return NewMemory()
 	}
 
-	switch strings.ToLower(c.Driver) {
+	switch driver := strings.ToLower(c.Driver); driver {
 	case sqliteDriver:
-		dbClient := db.MustCreateClient(sqliteDriver, db.Config{
-			Driver: c.Driver,
-			DSN:    sqlitePersistencePath,
-		})
+		c.Driver, c.DSN = driver, sqlitePersistencePath
 
 		log.Info("database", "initializing db client with", sqliteDriver, "ensure to persist the path", sqlitePersistencePath)
+		dbClient, err := openDatabase(log, c)
+		if err != nil {
+			log.Error(err, "database", "opening db client error", err, "fallback to", "memory_store")
+			return NewMemory()
+		}
 		return &Persistence{ScalingOperation: NewSqliteScalingOperationStore(dbClient)}
 
 	case postgresDriver:
-		dbClient := db.MustCreateClient(postgresDriver, db.Config{
-			Driver: c.Driver,
-			DSN:    c.DSN,
-		})
+		c.Driver = driver
 
 		log.Info("database", "initializing db client with", postgresDriver)
+		dbClient, err := openDatabase(log, c)
+		if err != nil {
+			log.Error(err, "database", "opening db client error", err, "fallback to", "memory_store")
+			return NewMemory()
+		}
 		return &Persistence{ScalingOperation: NewPostgresScalingOperationStore(dbClient)}
 
+	case mysqlDriver:
+		c.Driver = driver
+
+		log.Info("database", "initializing db client with", mysqlDriver)
+		dbClient, err := openDatabase(log, c)
+		if err != nil {
+			log.Error(err, "database", "opening db client error", err, "fallback to", "memory_store")
+			return NewMemory()
+		}
+		return &Persistence{ScalingOperation: NewMysqlScalingOperationStore(dbClient)}
+
+	case annotationsDriver:
+		log.Info("database", "initializing store with", annotationsDriver, "replicas are recorded on the objects", "no database is required")
+		return &Persistence{ScalingOperation: NewAnnotationScalingOperationStore(k8sClient)}
+
+	case configMapDriver:
+		namespace, err := utils.GetNamespace()
+		if err != nil {
+			namespace = "kubetime-scaler"
+		}
+
+		log.Info("database", "initializing store with", configMapDriver, "namespace", namespace, "no database is required")
+		return &Persistence{ScalingOperation: NewConfigMapScalingOperationStore(k8sClient, apiReader, namespace)}
+
 	default:
-		log.Info("database", "database flag is set to true but none of sqlite or postgres driver were configured", c.Driver, "fallback to", "memory_store")
-		return nil
+		log.Info("database", "database flag is set to true but none of sqlite, postgres, mysql, annotations or configmap driver were configured", c.Driver, "fallback to", "memory_store")
+		return NewMemory()
+	}
+}
+
+// openDatabase connects to the database, retrying with db.ConnectBackoff for at most
+// db.ConnectTimeout. A database still down after the retries does not stop the manager: the client
+// is returned anyway, the DatabaseAvailable condition reports it and the schema is migrated once
+// the database answers.
+func openDatabase(log logr.Logger, c db.Config) (*sql.DB, error) {
+	dbClient, err := db.Open(c)
+	if err != nil {
+		return nil, err
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), db.ConnectTimeout)
+	defer cancel()
+
+	if err := db.WaitForConnection(ctx, dbClient, db.ConnectBackoff, func(attempt int, err error) {
+		log.Info("database", "driver", c.Driver, "connection attempt", attempt, "failed", err.Error())
+	}); err != nil {
+		log.Error(err, "database", "driver", c.Driver, "database is unavailable, starting without it", err)
+	}
+
+	return dbClient, nil
 }
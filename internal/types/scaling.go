@@ -1,5 +1,7 @@
 package types
 
+// ScalingOperation is the direction of a scaling job. The replicas it scales to come from the rule,
+// the object annotations or the store, never from the operation value.
 type ScalingOperation int
 
 const (
@@ -7,13 +9,30 @@ const (
 	OperationUpscale
 )
 
+func (o ScalingOperation) String() string {
+	if o == OperationUpscale {
+		return "upscale"
+	}
+	return "downscale"
+}
+
 type ResourceType string
 
 const (
-	DeploymentObjectResource  ResourceType = "deployments"
-	StatefulSetObjectResource ResourceType = "statefulset"
+	DeploymentObjectResource              ResourceType = "deployments"
+	StatefulSetObjectResource             ResourceType = "statefulset"
+	HorizontalPodAutoscalerObjectResource ResourceType = "hpa"
+	CronJobObjectResource                 ResourceType = "cronjobs"
 )
 
 func (r ResourceType) String() string {
 	return string(r)
 }
+
+// SupportedResourceTypes lists every resource type with a registered scaler.
+var SupportedResourceTypes = []ResourceType{
+	DeploymentObjectResource,
+	StatefulSetObjectResource,
+	HorizontalPodAutoscalerObjectResource,
+	CronJobObjectResource,
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronParser matches the parser used by the scheduler (cron.WithSeconds).
+var CronParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// BuildCronExpression turns an HH:MM or HH:MM:SS time and a day of week recurrence
+// into a cron expression with seconds.
+func BuildCronExpression(recurrence, timeStr string) (string, error) {
+	timeStrParts := strings.Split(timeStr, ":")
+	if len(timeStrParts) == 2 {
+		timeStr = timeStr + ":00"
+	}
+	t, err := time.Parse("15:04:05", timeStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid time format %q: %v", timeStr, err)
+	}
+
+	if recurrence == "*" || recurrence == "@daily" {
+		return fmt.Sprintf("%d %d %d * * *", t.Second(), t.Minute(), t.Hour()), nil
+	}
+
+	return fmt.Sprintf("%d %d %d * * %s", t.Second(), t.Minute(), t.Hour(), recurrence), nil
+}
+
+// NormalizeCronExpression validates a cron expression written by the user and returns it in the
+// format of the scheduler: five field expressions get a leading "0" seconds field, six field ones
+// and descriptors such as @hourly or "@every 2h" are kept. The time zone is set by the rule, the
+// TZ= and CRON_TZ= prefixes are rejected.
+func NormalizeCronExpression(expression string) (string, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") {
+		return "", fmt.Errorf("invalid cron expression %q: set the time zone with timeZone instead of a prefix", expression)
+	}
+
+	if !strings.HasPrefix(expression, "@") && len(strings.Fields(expression)) == 5 {
+		expression = "0 " + expression
+	}
+
+	if _, err := CronParser.Parse(expression); err != nil {
+		return "", fmt.Errorf("invalid cron expression %q: %v", expression, err)
+	}
+
+	return expression, nil
+}
+
+// ScheduleExpression returns the expression of one side of a window: the raw cron expression when
+// it is set, otherwise the one built from the time and the recurrence. The result always parses.
+func ScheduleExpression(cronExpression, recurrence, timeStr string) (string, error) {
+	if cronExpression != "" {
+		return NormalizeCronExpression(cronExpression)
+	}
+
+	expression, err := BuildCronExpression(recurrence, timeStr)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := CronParser.Parse(expression); err != nil {
+		return "", fmt.Errorf("invalid cron expression %q: %v", expression, err)
+	}
+
+	return expression, nil
+}
+
+// previousActivationLookbacks are the periods searched, in order, for the last activation of a
+// schedule: the short ones find frequent schedules in few steps, the long ones monthly and yearly
+// schedules.
+var previousActivationLookbacks = []time.Duration{
+	time.Minute,
+	time.Hour,
+	25 * time.Hour,
+	8 * 24 * time.Hour,
+	32 * 24 * time.Hour,
+	367 * 24 * time.Hour,
+	4*366*24*time.Hour + time.Hour,
+}
+
+// PreviousActivation returns the last activation of the schedule at or before now, as cron only
+// computes the next one. found is false when the schedule did not run during the last four years.
+func PreviousActivation(schedule cron.Schedule, now time.Time) (previous time.Time, found bool) {
+	for _, lookback := range previousActivationLookbacks {
+		for next := schedule.Next(now.Add(-lookback)); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+			previous, found = next, true
+		}
+		if found {
+			return previous, true
+		}
+	}
+	return time.Time{}, false
+}